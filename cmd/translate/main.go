@@ -44,7 +44,7 @@ func main() {
 		defaultPDFFont = path
 	}
 
-	flag.StringVar(&cfg.Format, "format", config.StringOrFallback(cfgFile.Format, "auto"), "input format: text|md|pdf|auto")
+	flag.StringVar(&cfg.Format, "format", config.StringOrFallback(cfgFile.Format, "auto"), "input format: text|md|pdf|code|auto")
 	flag.StringVar(&cfg.InPath, "in", "", "input path (default: stdin)")
 	flag.StringVar(&cfg.OutPath, "out", "", "output path (default: stdout)")
 	flag.StringVar(&cfg.From, "from", config.StringOrFallback(cfgFile.From, "auto"), "source language code (default: auto)")
@@ -58,9 +58,29 @@ func main() {
 	flag.IntVar(&cfg.MaxChars, "max-chars", config.IntOrFallback(cfgFile.MaxChars, 2000), "max chars per translation request (0 disables)")
 	flag.StringVar(&cfg.Endpoint, "endpoint", config.StringOrFallback(cfgFile.Endpoint, "completion"), "endpoint: chat|completion|auto")
 	flag.DurationVar(&cfg.PassphraseTTL, "passphrase-ttl", config.PassphraseTTL(cfgFile, 10*time.Minute), "cache passphrase for duration (0 disables)")
-	flag.StringVar(&cfg.DumpExtracted, "dump-extracted", "", "dump raw extracted PDF text to path (use - for stdout)")
+	flag.StringVar(&cfg.DumpExtracted, "dump-extracted", "", "dump extracted PDF text blocks in reading order to path (use - for stdout)")
+	flag.StringVar(&cfg.DumpFormat, "dump-format", "text", "format of --dump-extracted: text|json")
 	flag.BoolVar(&cfg.VerbosePrompt, "verbose-prompt", false, "print prompts to stderr")
 	flag.StringVar(&cfg.PDFFont, "pdf-font", config.StringOrFallback(cfgFile.PDFFont, defaultPDFFont), "TTF font file for PDF overlay")
+	flag.StringVar(&cfg.PDFFontBold, "pdf-font-bold", cfgFile.PDFFontBold, "TTF font file for bold PDF text (default: LINESeedJP-Bold.ttf next to the default font)")
+	flag.StringVar(&cfg.PDFFontItalic, "pdf-font-italic", cfgFile.PDFFontItalic, "TTF font file for italic PDF text (default: regular font)")
+	flag.StringVar(&cfg.PDFFontBoldItalic, "pdf-font-bold-italic", cfgFile.PDFFontBoldItalic, "TTF font file for bold italic PDF text (default: bold font)")
+	flag.StringVar(&cfg.PDFFonts, "pdf-fonts", strings.Join(cfgFile.PDFFonts, ","), "comma separated fallback TTF fonts for characters --pdf-font lacks")
+	flag.StringVar(&cfg.PDFLayout, "pdf-layout", "overlay", "PDF output layout: overlay|side-by-side|interleave-pages")
+	flag.Float64Var(&cfg.PDFMinFont, "pdf-min-font-size", 6, "smallest font size used to fit translations into PDF text boxes")
+	flag.StringVar(&cfg.PDFPassword, "pdf-password", "", "password for encrypted PDF input (default: TRANSLATE_PDF_PASSWORD, or prompt)")
+	flag.BoolVar(&cfg.PDFKeepEncryption, "pdf-keep-encryption", false, "encrypt the translated PDF with the input password and permissions")
+	flag.StringVar(&cfg.PDFPages, "pages", "", "PDF pages to translate, e.g. 1-3,10,20- (default: all)")
+	flag.BoolVar(&cfg.PDFPagesOnly, "pages-only", false, "with --pages, write only the selected pages instead of keeping the others untranslated")
+	flag.StringVar(&cfg.PDFRedaction, "pdf-redact", "cover", "how to hide the original PDF text: cover (background-coloured boxes) or remove (delete text operators)")
+	flag.BoolVar(&cfg.PDFVertical, "pdf-vertical", false, "draw translations of vertically written PDF text as upright vertical columns (for CJK targets)")
+	flag.BoolVar(&cfg.MarkdownHTML, "md-html", false, "translate text and alt/title attributes inside HTML in Markdown")
+	flag.BoolVar(&cfg.MarkdownCodeComments, "md-code-comments", false, "translate comments in fenced code blocks with a known language")
+	flag.BoolVar(&cfg.MarkdownCodeStrings, "md-code-strings", false, "with --md-code-comments, also translate prose-like string literals")
+	flag.StringVar(&cfg.HeadingAnchors, "md-heading-anchors", "", "keep heading links working: attr ({#slug}), html (<a id>) or rewrite (fragment links)")
+	flag.StringVar(&cfg.Bilingual, "bilingual", "", "keep the source next to the translation: interleave (text, md) or table (md)")
+	flag.StringVar(&cfg.CodeLang, "code-lang", "", "source language for --format code (default: from file extension)")
+	flag.StringVar(&cfg.FrontMatterKeys, "front-matter-keys", "", "comma separated Markdown front matter keys to translate (e.g. title,description)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "translate - translate text/markdown/pdf via OpenAI compatible API\n\n")
@@ -69,6 +89,7 @@ func main() {
 		fmt.Fprintln(os.Stderr, "  translate --from en --to ja --in input.txt --out output.txt")
 		fmt.Fprintln(os.Stderr, "  cat input.md | translate --format md --to ja > output.md")
 		fmt.Fprintln(os.Stderr, "  translate --format pdf --in input.pdf --out output.pdf")
+		fmt.Fprintln(os.Stderr, "  translate --from ja --to en --in main.go --out main.go")
 		fmt.Fprintln(os.Stderr, "\nConfig:")
 		fmt.Fprintln(os.Stderr, "  translate config set --base-url http://your-host:8080 --model gpt-oss-20b")
 		fmt.Fprintln(os.Stderr, "\nSecrets:")
@@ -77,6 +98,14 @@ func main() {
 
 	flag.Parse()
 
+	if cfg.PDFFontBold == "" && cfg.PDFFont == defaultPDFFont {
+		if path, err := config.DefaultPDFBoldFontPath(); err == nil {
+			if _, err := os.Stat(path); err == nil {
+				cfg.PDFFontBold = path
+			}
+		}
+	}
+
 	if err := app.Run(context.Background(), cfg); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
@@ -156,6 +185,10 @@ func configSet(args []string) error {
 	endpoint := fs.String("endpoint", "", "endpoint: chat|completion|auto")
 	passphraseTTL := fs.Duration("passphrase-ttl", 0, "cache passphrase for duration")
 	pdfFont := fs.String("pdf-font", "", "TTF font file for PDF overlay")
+	pdfFontBold := fs.String("pdf-font-bold", "", "TTF font file for bold PDF text")
+	pdfFontItalic := fs.String("pdf-font-italic", "", "TTF font file for italic PDF text")
+	pdfFontBoldItalic := fs.String("pdf-font-bold-italic", "", "TTF font file for bold italic PDF text")
+	pdfFonts := fs.String("pdf-fonts", "", "comma separated fallback TTF fonts")
 
 	if err := fs.Parse(args); err != nil {
 		return err
@@ -188,6 +221,19 @@ func configSet(args []string) error {
 			current.PassphraseTTLSeconds = int(passphraseTTL.Seconds())
 		case "pdf-font":
 			current.PDFFont = *pdfFont
+		case "pdf-font-bold":
+			current.PDFFontBold = *pdfFontBold
+		case "pdf-font-italic":
+			current.PDFFontItalic = *pdfFontItalic
+		case "pdf-font-bold-italic":
+			current.PDFFontBoldItalic = *pdfFontBoldItalic
+		case "pdf-fonts":
+			current.PDFFonts = nil
+			for _, path := range strings.Split(*pdfFonts, ",") {
+				if path = strings.TrimSpace(path); path != "" {
+					current.PDFFonts = append(current.PDFFonts, path)
+				}
+			}
 		}
 	})
 
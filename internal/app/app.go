@@ -11,34 +11,55 @@ import (
 	"time"
 
 	"github.com/fuba/translate/internal/chunk"
+	"github.com/fuba/translate/internal/code"
 	"github.com/fuba/translate/internal/lang"
 	"github.com/fuba/translate/internal/llm"
 	"github.com/fuba/translate/internal/markdown"
-	progressui "github.com/fuba/translate/internal/progress"
 	"github.com/fuba/translate/internal/pdf"
+	progressui "github.com/fuba/translate/internal/progress"
 	"github.com/fuba/translate/internal/secure"
 	"github.com/fuba/translate/internal/translate"
 	"golang.org/x/term"
 )
 
 type Config struct {
-	Format        string
-	InPath        string
-	OutPath       string
-	From          string
-	To            string
-	Model         string
-	BaseURL       string
-	APIKey        string
-	Timeout       time.Duration
-	Verbose       bool
-	Silent        bool
-	MaxChars      int
-	Endpoint      string
-	PassphraseTTL time.Duration
-	DumpExtracted string
-	VerbosePrompt bool
-	PDFFont       string
+	Format               string
+	InPath               string
+	OutPath              string
+	From                 string
+	To                   string
+	Model                string
+	BaseURL              string
+	APIKey               string
+	Timeout              time.Duration
+	Verbose              bool
+	Silent               bool
+	MaxChars             int
+	Endpoint             string
+	PassphraseTTL        time.Duration
+	DumpExtracted        string
+	DumpFormat           string
+	VerbosePrompt        bool
+	PDFFont              string
+	PDFFontBold          string
+	PDFFontItalic        string
+	PDFFontBoldItalic    string
+	PDFFonts             string
+	PDFLayout            string
+	PDFMinFont           float64
+	PDFPassword          string
+	PDFKeepEncryption    bool
+	PDFPages             string
+	PDFPagesOnly         bool
+	PDFRedaction         string
+	PDFVertical          bool
+	FrontMatterKeys      string
+	MarkdownHTML         bool
+	MarkdownCodeComments bool
+	MarkdownCodeStrings  bool
+	HeadingAnchors       string
+	Bilingual            string
+	CodeLang             string
 }
 
 func Run(ctx context.Context, cfg Config) error {
@@ -46,6 +67,20 @@ func Run(ctx context.Context, cfg Config) error {
 	if err != nil {
 		return err
 	}
+	switch cfg.HeadingAnchors {
+	case "", markdown.AnchorsAttr, markdown.AnchorsHTML, markdown.AnchorsRewrite:
+	default:
+		return fmt.Errorf("unknown heading anchor mode: %s", cfg.HeadingAnchors)
+	}
+	switch cfg.Bilingual {
+	case "":
+	case markdown.BilingualInterleave, markdown.BilingualTable:
+		if format != "md" && (format != "text" || cfg.Bilingual == markdown.BilingualTable) {
+			return fmt.Errorf("--bilingual %s is not supported for %s input", cfg.Bilingual, format)
+		}
+	default:
+		return fmt.Errorf("unknown bilingual layout: %s", cfg.Bilingual)
+	}
 
 	if strings.TrimSpace(cfg.To) == "" {
 		cfg.To = lang.DefaultTargetLang(os.Getenv("LANG"))
@@ -102,6 +137,21 @@ func Run(ctx context.Context, cfg Config) error {
 		if err != nil {
 			return err
 		}
+		if cfg.Bilingual != "" {
+			paragraphs := chunk.Paragraphs(string(input))
+			if reporter != nil {
+				total := 0
+				for _, p := range paragraphs {
+					total += len(chunk.Split(p, cfg.MaxChars))
+				}
+				reporter.SetTotal(total)
+			}
+			out, err := translateBilingualText(ctx, client, paragraphs, cfg.From, cfg.To, cfg.MaxChars, progressFn)
+			if err != nil {
+				return err
+			}
+			return writeOutput(cfg.OutPath, []byte(out))
+		}
 		if reporter != nil {
 			reporter.SetTotal(len(chunk.Split(string(input), cfg.MaxChars)))
 		}
@@ -115,10 +165,45 @@ func Run(ctx context.Context, cfg Config) error {
 		if err != nil {
 			return err
 		}
+		mdOpts := []markdown.Option{
+			markdown.WithFrontMatterKeys(splitList(cfg.FrontMatterKeys)),
+			markdown.WithHTML(cfg.MarkdownHTML),
+			markdown.WithCodeComments(cfg.MarkdownCodeComments),
+			markdown.WithCodeStrings(cfg.MarkdownCodeStrings),
+			markdown.WithHeadingAnchors(cfg.HeadingAnchors),
+			markdown.WithBilingual(cfg.Bilingual),
+		}
+		if reporter != nil {
+			mdOpts = append(mdOpts, markdown.WithReport(reporter))
+		} else if !cfg.Silent {
+			mdOpts = append(mdOpts, markdown.WithReport(os.Stderr))
+		}
+		if reporter != nil {
+			reporter.SetTotal(markdown.CountChunks(input, cfg.MaxChars, mdOpts...))
+		}
+		out, err := markdown.TranslateWithProgress(ctx, client, input, cfg.From, cfg.To, cfg.MaxChars, progressFn, mdOpts...)
+		if err != nil {
+			return err
+		}
+		return writeOutput(cfg.OutPath, out)
+	case "code":
+		input, err := readInput(cfg.InPath)
+		if err != nil {
+			return err
+		}
+		language := code.LanguageFromInfo(cfg.CodeLang)
+		if language == "" {
+			language = code.LanguageFromPath(cfg.InPath)
+		}
+		if language == "" {
+			return errors.New("code format requires a known source file extension or --code-lang")
+		}
 		if reporter != nil {
-			reporter.SetTotal(markdown.CountChunks(input, cfg.MaxChars))
+			reporter.SetTotal(code.CountChunks(input, language, cfg.MaxChars))
 		}
-		out, err := markdown.TranslateWithProgress(ctx, client, input, cfg.From, cfg.To, cfg.MaxChars, progressFn)
+		out, err := code.Translate(input, language, func(text string) (string, error) {
+			return translateText(ctx, client, text, cfg.From, cfg.To, cfg.MaxChars, progressFn)
+		})
 		if err != nil {
 			return err
 		}
@@ -127,32 +212,60 @@ func Run(ctx context.Context, cfg Config) error {
 		if cfg.InPath == "" || cfg.InPath == "-" {
 			return errors.New("pdf input requires a file path")
 		}
-		if strings.TrimSpace(cfg.DumpExtracted) != "" {
-			unidocKey, err := secure.LoadUnidocKey(cfg.PassphraseTTL)
-			if err != nil {
-				return err
-			}
-			text, err := pdf.ExtractText(cfg.InPath, unidocKey)
-			if err != nil {
-				return err
-			}
-			return writeOutput(cfg.DumpExtracted, []byte(text))
+		pages, err := pdf.ParsePages(cfg.PDFPages)
+		if err != nil {
+			return err
+		}
+		pdfOpts := []pdf.Option{
+			pdf.WithLayout(cfg.PDFLayout),
+			pdf.WithMinFontSize(cfg.PDFMinFont),
+			pdf.WithPassword(secure.PDFPassword(cfg.PDFPassword)),
+			pdf.WithPasswordPrompt(secure.PromptPDFPassword),
+			pdf.WithKeepEncryption(cfg.PDFKeepEncryption),
+			pdf.WithPages(pages),
+			pdf.WithSelectedPagesOnly(cfg.PDFPagesOnly),
+			pdf.WithRedaction(cfg.PDFRedaction),
+			pdf.WithStyleFonts(cfg.PDFFontBold, cfg.PDFFontItalic, cfg.PDFFontBoldItalic),
+			pdf.WithFallbackFonts(splitList(cfg.PDFFonts)),
+			pdf.WithVerticalText(cfg.PDFVertical),
 		}
-		if cfg.OutPath == "" || cfg.OutPath == "-" {
+		if reporter != nil {
+			pdfOpts = append(pdfOpts, pdf.WithReport(reporter))
+		} else if !cfg.Silent {
+			pdfOpts = append(pdfOpts, pdf.WithReport(os.Stderr))
+		}
+		dump := strings.TrimSpace(cfg.DumpExtracted) != ""
+		switch cfg.DumpFormat {
+		case "", pdf.DumpText, pdf.DumpJSON:
+		default:
+			return fmt.Errorf("unknown dump format: %s", cfg.DumpFormat)
+		}
+		if !dump && (cfg.OutPath == "" || cfg.OutPath == "-") {
 			return errors.New("pdf output requires a file path")
 		}
 		unidocKey, err := secure.LoadUnidocKey(cfg.PassphraseTTL)
 		if err != nil {
 			return err
 		}
-		if reporter != nil {
-			total, err := pdf.CountChunks(cfg.InPath, unidocKey, cfg.MaxChars)
-			if err != nil {
-				return err
+		doc, err := pdf.Open(cfg.InPath, unidocKey, pdfOpts...)
+		if err != nil {
+			return err
+		}
+		defer doc.Close()
+		if dump {
+			if cfg.DumpFormat == pdf.DumpJSON {
+				data, err := doc.JSON()
+				if err != nil {
+					return err
+				}
+				return writeOutput(cfg.DumpExtracted, data)
 			}
-			reporter.SetTotal(total)
+			return writeOutput(cfg.DumpExtracted, []byte(doc.Text()))
+		}
+		if reporter != nil {
+			reporter.SetTotal(doc.CountChunks(cfg.MaxChars))
 		}
-		return pdf.Translate(ctx, client, cfg.InPath, cfg.OutPath, cfg.From, cfg.To, unidocKey, cfg.MaxChars, progressFn, cfg.PDFFont)
+		return doc.Translate(ctx, client, cfg.OutPath, cfg.From, cfg.To, cfg.MaxChars, progressFn, cfg.PDFFont)
 	default:
 		return fmt.Errorf("unsupported format: %s", format)
 	}
@@ -174,6 +287,27 @@ func translateText(ctx context.Context, tr translate.Translator, text, from, to
 	return b.String(), nil
 }
 
+// translateBilingualText emits each paragraph followed by its translation.
+func translateBilingualText(ctx context.Context, tr translate.Translator, paragraphs []string, from, to string, maxChars int, progress func(string)) (string, error) {
+	var b strings.Builder
+	for i, p := range paragraphs {
+		out, err := translateText(ctx, tr, p, from, to, maxChars, progress)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(p)
+		b.WriteString("\n\n")
+		b.WriteString(strings.Trim(out, "\r\n"))
+	}
+	if len(paragraphs) > 0 {
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
+
 func promptLogger(enabled bool) func(string) {
 	if !enabled {
 		return nil
@@ -183,6 +317,16 @@ func promptLogger(enabled bool) func(string) {
 	}
 }
 
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func resolveFormat(format, inPath string) (string, error) {
 	f := strings.ToLower(strings.TrimSpace(format))
 	if f == "" || f == "auto" {
@@ -190,7 +334,7 @@ func resolveFormat(format, inPath string) (string, error) {
 	}
 
 	switch f {
-	case "text", "md", "markdown", "pdf":
+	case "text", "md", "markdown", "pdf", "code":
 		if f == "markdown" {
 			return "md", nil
 		}
@@ -210,9 +354,11 @@ func detectFormatFromPath(inPath string) string {
 		return "md"
 	case ".pdf":
 		return "pdf"
-	default:
-		return "text"
 	}
+	if code.LanguageFromPath(inPath) != "" {
+		return "code"
+	}
+	return "text"
 }
 
 func readInput(path string) ([]byte, error) {
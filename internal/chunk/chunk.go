@@ -1,5 +1,24 @@
 package chunk
 
+import (
+	"regexp"
+	"strings"
+)
+
+var blankLineRe = regexp.MustCompile(`\r?\n[ \t]*\r?\n\s*`)
+
+// Paragraphs splits text at blank lines and returns the non-blank
+// paragraphs without their surrounding line breaks.
+func Paragraphs(text string) []string {
+	var out []string
+	for _, p := range blankLineRe.Split(text, -1) {
+		if strings.TrimSpace(p) != "" {
+			out = append(out, strings.Trim(p, "\r\n"))
+		}
+	}
+	return out
+}
+
 func Split(text string, maxChars int) []string {
 	if maxChars <= 0 {
 		return []string{text}
@@ -32,3 +32,16 @@ func TestSplitNoMax(t *testing.T) {
 		t.Fatalf("unexpected chunks: %v", chunks)
 	}
 }
+
+func TestParagraphs(t *testing.T) {
+	got := Paragraphs("\n  First line\nsecond line\n\n \n\nNext\r\n\r\nLast\n")
+	want := []string{"  First line\nsecond line", "Next", "Last"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package code translates comments, and optionally string literals, in
+// source code while leaving every other byte untouched.
+package code
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/fuba/translate/internal/chunk"
+)
+
+// TextFunc translates one piece of plain text.
+type TextFunc func(text string) (string, error)
+
+type Option func(*options)
+
+type options struct {
+	strings bool
+}
+
+// WithStrings also translates string literals that read like prose.
+func WithStrings(enabled bool) Option {
+	return func(o *options) {
+		o.strings = enabled
+	}
+}
+
+// directiveRe matches comments that are read by tools rather than people,
+// including cgo's "//export Name" and generated-code markers.
+var directiveRe = regexp.MustCompile(`^(!|go:|\+build|line \d|nolint|noqa|eslint|prettier-|pylint:|type:|-\*-|vim?:|@ts-|lint:|#?nosec\b|export [\pL_][\pL\pN_]*$|Code generated .* DO NOT EDIT\.$)`)
+
+type edit struct {
+	start int
+	stop  int
+	text  string
+}
+
+// extensions maps source file extensions to lexer languages. Markup and
+// configuration formats are left to the other input formats.
+var extensions = map[string]string{
+	".go": "go", ".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".cxx": "cpp",
+	".hh": "cpp", ".hpp": "cpp", ".java": "java", ".cs": "cs", ".rs": "rust",
+	".dart": "dart", ".kt": "kotlin", ".kts": "kotlin", ".swift": "swift",
+	".scala": "scala", ".js": "js", ".mjs": "js", ".cjs": "js", ".jsx": "js",
+	".ts": "ts", ".tsx": "ts", ".py": "python", ".rb": "ruby", ".sh": "sh",
+	".bash": "bash", ".zsh": "zsh", ".pl": "perl", ".r": "r", ".sql": "sql",
+	".lua": "lua", ".hs": "haskell", ".php": "php", ".css": "css",
+	".scss": "scss", ".less": "less",
+}
+
+// LanguageFromPath returns the language of a source file from its name, or
+// "" when it is not a known source file.
+func LanguageFromPath(path string) string {
+	switch strings.ToLower(filepath.Base(path)) {
+	case "makefile", "gnumakefile":
+		return "make"
+	case "dockerfile":
+		return "dockerfile"
+	}
+	return extensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// CountChunks returns the number of translation requests Translate would make
+// when each comment is split by maxChars.
+func CountChunks(src []byte, lang string, maxChars int, opts ...Option) int {
+	n := 0
+	_, _ = Translate(src, lang, func(text string) (string, error) {
+		n += len(chunk.Split(text, maxChars))
+		return text, nil
+	}, opts...)
+	return n
+}
+
+// Translate rewrites the comments and docstrings of src, a program in lang,
+// with tr. Each comment paragraph is translated on its own and re-wrapped to
+// the width of the original comment. Translations that would change the
+// code around them, such as a block comment that now contains its own
+// terminator, are dropped.
+func Translate(src []byte, lang string, tr TextFunc, opts ...Option) ([]byte, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	spans := lex(lang, src)
+	var edits []edit
+	for _, block := range collectComments(src, spans) {
+		for _, p := range block.paragraphs(src) {
+			if !isProse(p.text) {
+				continue
+			}
+			out, err := tr(p.text)
+			if err != nil {
+				return nil, err
+			}
+			rendered := block.render(src, p, out)
+			if block.close != "" && strings.Contains(rendered, block.close) {
+				continue
+			}
+			edits = append(edits, edit{start: p.lines[0].textStart, stop: p.lines[len(p.lines)-1].textStop, text: rendered})
+		}
+	}
+	if o.strings {
+		for _, sp := range spans {
+			if sp.kind != spanString {
+				continue
+			}
+			value := string(src[sp.textStart:sp.textStop])
+			if !isProse(value) || strings.ContainsAny(value, "\\${}%\n") || !strings.Contains(value, " ") {
+				continue
+			}
+			out, err := tr(value)
+			if err != nil {
+				return nil, err
+			}
+			out = strings.Join(strings.Fields(out), " ")
+			if strings.Contains(out, sp.close) || strings.Contains(out, "\\") {
+				continue
+			}
+			edits = append(edits, edit{start: sp.textStart, stop: sp.textStop, text: out})
+		}
+	}
+	return applyVerified(src, lang, edits, o.strings), nil
+}
+
+// applyVerified applies edits and checks the result against src: every
+// byte outside the edits must be that of src, and, lexed again, every byte
+// an edit wrote must fall inside a comment (or a string literal with
+// withStrings) and the code tokens outside them must match those of src
+// byte for byte. Edits that break a check are dropped, the ones behind the
+// first changed token first, until the code matches.
+func applyVerified(src []byte, lang string, edits []edit, withStrings bool) []byte {
+	want := codeTokens(src, lex(lang, src), withStrings)
+	edits = append([]edit(nil), edits...)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	for len(edits) > 0 {
+		out := applyEdits(src, edits)
+		if !unchangedOutside(src, out, edits) {
+			return src
+		}
+		spans := lex(lang, out)
+		starts := outputStarts(edits)
+		kept := edits[:0:0]
+		for i, e := range edits {
+			if covered(out, spans, starts[i], starts[i]+len(e.text)) {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) < len(edits) {
+			edits = kept
+			continue
+		}
+		got := codeTokens(out, spans, withStrings)
+		changed := firstChange(want, got)
+		if changed < 0 {
+			return out
+		}
+		pos := len(out)
+		if changed < len(got) {
+			pos = got[changed].pos
+		}
+		drop := 0
+		for i, start := range starts {
+			if start < pos {
+				drop = i
+			}
+		}
+		edits = append(edits[:drop:drop], edits[drop+1:]...)
+	}
+	return src
+}
+
+// unchangedOutside reports whether out, src with edits applied, has the
+// bytes of src everywhere outside edits, which are sorted by start.
+func unchangedOutside(src, out []byte, edits []edit) bool {
+	last, shift := 0, 0
+	for _, e := range edits {
+		if e.start < last || !bytes.Equal(src[last:e.start], out[last+shift:e.start+shift]) {
+			return false
+		}
+		shift += len(e.text) - (e.stop - e.start)
+		last = e.stop
+	}
+	return bytes.Equal(src[last:], out[last+shift:])
+}
+
+// outputStarts returns where each of edits, sorted by start, begins in the
+// edited text.
+func outputStarts(edits []edit) []int {
+	starts := make([]int, len(edits))
+	shift := 0
+	for i, e := range edits {
+		starts[i] = e.start + shift
+		shift += len(e.text) - (e.stop - e.start)
+	}
+	return starts
+}
+
+// covered reports whether every non-blank byte of src[start:stop] lies in
+// one of spans, which are sorted by position.
+func covered(src []byte, spans []span, start, stop int) bool {
+	k := sort.Search(len(spans), func(i int) bool { return spans[i].stop > start })
+	for pos := start; pos < stop; {
+		for k < len(spans) && spans[k].stop <= pos {
+			k++
+		}
+		switch {
+		case k < len(spans) && spans[k].start <= pos:
+			pos = spans[k].stop
+		case isSpace(src[pos]):
+			pos++
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// codeToken is a run of non-blank code bytes and its offset.
+type codeToken struct {
+	text string
+	pos  int
+}
+
+// codeTokens returns the code of src split at blanks and at comments and
+// docstrings (and string literal contents with withStrings), so that
+// re-wrapped comments leave the tokens unchanged.
+func codeTokens(src []byte, spans []span, withStrings bool) []codeToken {
+	var tokens []codeToken
+	add := func(start, stop int) {
+		for pos := start; pos < stop; {
+			if isSpace(src[pos]) {
+				pos++
+				continue
+			}
+			end := pos
+			for end < stop && !isSpace(src[end]) {
+				end++
+			}
+			tokens = append(tokens, codeToken{text: string(src[pos:end]), pos: pos})
+			pos = end
+		}
+	}
+	last := 0
+	for _, sp := range spans {
+		start, stop := sp.start, sp.stop
+		if sp.kind == spanString {
+			if !withStrings {
+				continue
+			}
+			start, stop = sp.textStart, sp.textStop
+		}
+		add(last, start)
+		last = stop
+	}
+	add(last, len(src))
+	return tokens
+}
+
+// firstChange returns the index of the first token of got that differs
+// from want, or -1 when they are equal.
+func firstChange(want, got []codeToken) int {
+	for i := range min(len(want), len(got)) {
+		if want[i].text != got[i].text {
+			return i
+		}
+	}
+	if len(want) == len(got) {
+		return -1
+	}
+	return min(len(want), len(got))
+}
+
+// isProse reports whether text is worth translating.
+func isProse(text string) bool {
+	return strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
+
+func applyEdits(src []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	out := append([]byte(nil), src...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.stop:]...)...)
+	}
+	return out
+}
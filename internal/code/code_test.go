@@ -0,0 +1,232 @@
+package code
+
+import (
+	"strings"
+	"testing"
+)
+
+func upper(text string) (string, error) {
+	return strings.ToUpper(text), nil
+}
+
+func TestTranslateCommentsOnly(t *testing.T) {
+	src := "#!/bin/sh\n# Install the package\npip install \"my pkg\" # then run it\necho 'a#b'\n"
+	got, err := Translate([]byte(src), "sh", upper)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "#!/bin/sh\n# INSTALL THE PACKAGE\npip install \"my pkg\" # THEN RUN IT\necho 'a#b'\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestTranslateWrapsToOriginalWidth(t *testing.T) {
+	src := "func f() {\n\t// This comment explains what the function\n\t// does in two lines.\n\treturn\n}\n"
+	tr := func(string) (string, error) {
+		return "この関数が何をするのかを説明するコメントで、元のコメントと同じ幅で折り返されます。", nil
+	}
+	got, err := Translate([]byte(src), "go", tr)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	lines := strings.Split(string(got), "\n")
+	if !strings.HasPrefix(lines[1], "\t// この関数") || !strings.HasPrefix(lines[2], "\t// ") {
+		t.Fatalf("unexpected prefixes: %q", got)
+	}
+	for _, line := range lines[1:4] {
+		if displayWidth(line) > displayWidth("\t// This comment explains what the function") {
+			t.Fatalf("line too wide: %q", line)
+		}
+	}
+	if !strings.HasSuffix(string(got), "\treturn\n}\n") {
+		t.Fatalf("code changed: %q", got)
+	}
+}
+
+func TestTranslateBlockComment(t *testing.T) {
+	src := "/*\n * Parse the input.\n *\n * Returns an error.\n */\nint x = 1; /* inline note */\n"
+	got, err := Translate([]byte(src), "c", upper)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "/*\n * PARSE THE INPUT.\n *\n * RETURNS AN ERROR.\n */\nint x = 1; /* INLINE NOTE */\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestTranslateStrings(t *testing.T) {
+	src := "print(\"Hello, world\")\nopen('data.txt')\nprint(f\"{name} here\")\n"
+	got, err := Translate([]byte(src), "python", upper, WithStrings(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "print(\"HELLO, WORLD\")\nopen('data.txt')\nprint(f\"{name} here\")\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestLanguageFromInfo(t *testing.T) {
+	cases := map[string]string{
+		"python":          "python",
+		"JS title=app.js": "js",
+		"{.go}":           "go",
+		"":                "",
+		"mermaid":         "",
+	}
+	for info, want := range cases {
+		if got := LanguageFromInfo(info); got != want {
+			t.Fatalf("LanguageFromInfo(%q) = %q, want %q", info, got, want)
+		}
+	}
+}
+
+func TestTranslateGoKeepsDirectives(t *testing.T) {
+	src := "//go:build linux\n\n// Package x does things.\n//\n// Example:\n//\n//\tx.Run()\npackage x\n\n/*\n#include <stdio.h>\n*/\nimport \"C\"\n\n//go:generate stringer -type=Kind\nvar s = \"// not a comment\" // trailing note\n"
+	got, err := Translate([]byte(src), "go", upper)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "//go:build linux\n\n// PACKAGE X DOES THINGS.\n//\n// EXAMPLE:\n//\n//\tx.Run()\npackage x\n\n/*\n#include <stdio.h>\n*/\nimport \"C\"\n\n//go:generate stringer -type=Kind\nvar s = \"// not a comment\" // TRAILING NOTE\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestTranslatePythonDocstrings(t *testing.T) {
+	src := "def f():\n    \"\"\"Return the answer.\n\n    More details.\n    \"\"\"\n    x = \"\"\"not a docstring\"\"\" + y\n    return 42\n"
+	got, err := Translate([]byte(src), "python", upper)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "def f():\n    \"\"\"RETURN THE ANSWER.\n\n    MORE DETAILS.\n    \"\"\"\n    x = \"\"\"not a docstring\"\"\" + y\n    return 42\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestTranslateDropsEditsThatBreakCode(t *testing.T) {
+	src := "x = 1 /* note */\ny = 2 // other note\n"
+	tr := func(text string) (string, error) {
+		if text == "note" {
+			return "end */ here", nil
+		}
+		return "OTHER", nil
+	}
+	got, err := Translate([]byte(src), "c", tr)
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if string(got) != "x = 1 /* note */\ny = 2 // OTHER\n" {
+		t.Fatalf("got %q", got)
+	}
+	if firstChange(codeTokens(got, lex("c", got), false), codeTokens([]byte(src), lex("c", []byte(src)), false)) >= 0 {
+		t.Fatalf("code bytes changed: %q", got)
+	}
+}
+
+func TestApplyVerifiedDropsEditsOutsideComments(t *testing.T) {
+	src := []byte("x = 1 /* note */\ny = 2 // other\nz = 3 // last\n")
+	edits := []edit{
+		{start: 9, stop: 13, text: "a */ b /* c"},
+		{start: 26, stop: 31, text: "fine"},
+		{start: 41, stop: 45, text: "end\nw = 4"},
+	}
+	got := applyVerified(src, "c", edits, false)
+	if string(got) != "x = 1 /* note */\ny = 2 // fine\nz = 3 // last\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestTranslateKeepsCodeTheLexerMustNotReadAsComments(t *testing.T) {
+	cases := []struct {
+		lang, src, want string
+	}{
+		// Lifetimes are not character literals, so the quote in the string
+		// does not end one.
+		{"rust", "fn f<'a>(x: &'a str) -> &'a str { \"it's // here\" } // done\n",
+			"fn f<'a>(x: &'a str) -> &'a str { \"it's // here\" } // DONE\n"},
+		{"rust", "let c = '\\''; // quote\n", "let c = '\\''; // QUOTE\n"},
+		// Escaped slashes in a regular expression are not a comment, but a
+		// division is followed by one.
+		{"js", "const re = /a\\/\\/b/g; run(re) // run it\n", "const re = /a\\/\\/b/g; run(re) // RUN IT\n"},
+		{"js", "x = a / b // half\n", "x = a / b // HALF\n"},
+		{"js", "if (/[/*]/.test(s)) { go() } // check it\n", "if (/[/*]/.test(s)) { go() } // CHECK IT\n"},
+		// Long brackets hold strings and comments of any level.
+		{"lua", "local s = [==[ -- not a comment ]==] -- keep this\n", "local s = [==[ -- not a comment ]==] -- KEEP THIS\n"},
+		{"lua", "--[==[\nFirst ]] line\n]==]\nx = 1\n", "--[==[\nFIRST ]] LINE\n]==]\nx = 1\n"},
+	}
+	for _, c := range cases {
+		got, err := Translate([]byte(c.src), c.lang, upper)
+		if err != nil {
+			t.Fatalf("Translate error: %v", err)
+		}
+		if string(got) != c.want {
+			t.Errorf("%s: got %q\nwant %q", c.lang, got, c.want)
+		}
+	}
+}
+
+func TestUnchangedOutside(t *testing.T) {
+	src := []byte("a // b\nc // d\n")
+	edits := []edit{{start: 5, stop: 6, text: "B"}, {start: 12, stop: 13, text: "DD"}}
+	if !unchangedOutside(src, applyEdits(src, edits), edits) {
+		t.Fatal("edits reported as changing other bytes")
+	}
+	if unchangedOutside(src, []byte("a // B\nC // DD\n"), edits) {
+		t.Fatal("changed code byte not reported")
+	}
+}
+
+func TestTranslateKeepsToolComments(t *testing.T) {
+	cases := []string{
+		"//export Callback\nfunc Callback() {}\n",
+		"//lint:ignore U1000 kept for later\nfunc unused() {}\n",
+		"x := key // #nosec G101\n",
+		"// Code generated by stringer. DO NOT EDIT.\n\npackage x\n",
+	}
+	for _, src := range cases {
+		got, err := Translate([]byte(src), "go", upper)
+		if err != nil {
+			t.Fatalf("Translate error: %v", err)
+		}
+		if string(got) != src {
+			t.Fatalf("got %q, want %q", got, src)
+		}
+	}
+	got, err := Translate([]byte("// export the data to disk\n"), "go", upper)
+	if err != nil || string(got) != "// EXPORT THE DATA TO DISK\n" {
+		t.Fatalf("prose comment: %q, %v", got, err)
+	}
+}
+
+func TestLanguageFromPath(t *testing.T) {
+	cases := map[string]string{
+		"main.go":      "go",
+		"src/App.JAVA": "java",
+		"lib/util.py":  "python",
+		"Makefile":     "make",
+		"notes.txt":    "",
+		"config.yaml":  "",
+	}
+	for path, want := range cases {
+		if got := LanguageFromPath(path); got != want {
+			t.Fatalf("LanguageFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	src := []byte("ab\n\ncd\n")
+	idx := newLineIndex(src)
+	for pos, want := range []int{0, 0, 0, 1, 2, 2, 2, 3} {
+		if got := idx.number(pos); got != want {
+			t.Fatalf("number(%d) = %d, want %d", pos, got, want)
+		}
+	}
+	if got := idx.start(5); got != 4 {
+		t.Fatalf("start(5) = %d", got)
+	}
+}
@@ -0,0 +1,331 @@
+package code
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/width"
+)
+
+// commentLine is one physical line of comment text. The bytes between
+// lineStart and textStart are its prefix: indentation and the comment marker
+// or a block decoration such as " * "; markerEnd is where the marker or
+// decoration ends, so the bytes from there to textStart are the line's own
+// indentation.
+type commentLine struct {
+	lineStart int
+	markerEnd int
+	textStart int
+	textStop  int
+}
+
+func (l commentLine) indent(src []byte) int {
+	return displayWidth(string(src[l.markerEnd:l.textStart]))
+}
+
+// commentBlock is a run of line comments, or a single block comment, whose
+// text is translated paragraph by paragraph and re-wrapped to its width.
+type commentBlock struct {
+	lines []commentLine
+	// close is the block comment terminator, empty for line comments.
+	close string
+	block bool
+	// wrap is false for comments that share a line with code.
+	wrap bool
+}
+
+type paragraph struct {
+	lines []commentLine
+	text  string
+}
+
+// collectComments groups line comments that sit alone on consecutive lines
+// with the same marker and column, and turns block comments into blocks.
+func collectComments(src []byte, spans []span) []commentBlock {
+	var blocks []commentBlock
+	var current *commentBlock
+	var lastMarker string
+	var lastColumn, lastLine int
+	lines := newLineIndex(src)
+
+	flush := func() {
+		if current != nil {
+			blocks = append(blocks, *current)
+			current = nil
+		}
+	}
+
+	for _, sp := range spans {
+		switch sp.kind {
+		case spanLineComment:
+			lineStart := lines.start(sp.start)
+			alone := strings.TrimSpace(string(src[lineStart:sp.start])) == ""
+			line := lines.number(sp.start)
+			column := sp.start - lineStart
+			cl := commentLine{
+				lineStart: lineStart,
+				markerEnd: sp.textStart,
+				textStart: skipBlank(src, sp.textStart, sp.textStop),
+				textStop:  trimRightBlank(src, sp.textStart, sp.textStop),
+			}
+			if !alone {
+				flush()
+				blocks = append(blocks, commentBlock{lines: []commentLine{cl}})
+				continue
+			}
+			if current != nil && sp.open == lastMarker && column == lastColumn && line == lastLine+1 {
+				current.lines = append(current.lines, cl)
+			} else {
+				flush()
+				current = &commentBlock{lines: []commentLine{cl}, wrap: true}
+			}
+			lastMarker, lastColumn, lastLine = sp.open, column, line
+		case spanBlockComment, spanDocstring:
+			flush()
+			blocks = append(blocks, blockCommentLines(src, lines, sp))
+		}
+	}
+	flush()
+	return blocks
+}
+
+func blockCommentLines(src []byte, lines lineIndex, sp span) commentBlock {
+	block := commentBlock{close: sp.close, block: true, wrap: strings.TrimSpace(string(src[lines.start(sp.start):sp.start])) == ""}
+	pos := sp.textStart
+	for pos <= sp.textStop {
+		end := sp.textStop
+		if n := bytes.IndexByte(src[pos:sp.textStop], '\n'); n >= 0 {
+			end = pos + n
+		}
+		markerEnd := pos
+		if pos != sp.textStart && strings.HasSuffix(sp.open, "*") {
+			// Skip indentation and a leading "*" decoration.
+			if d := skipBlank(src, pos, end); d < end && src[d] == '*' {
+				markerEnd = d + 1
+			}
+		}
+		block.lines = append(block.lines, commentLine{
+			lineStart: lines.start(pos),
+			markerEnd: markerEnd,
+			textStart: skipBlank(src, markerEnd, end),
+			textStop:  trimRightBlank(src, markerEnd, end),
+		})
+		pos = end + 1
+	}
+	return block
+}
+
+// paragraphs splits the block at blank comment lines and tool directives.
+// Lines indented deeper than the rest of the block are taken as preformatted
+// text, such as code samples in doc comments, and left alone.
+func (b commentBlock) paragraphs(src []byte) []paragraph {
+	indent := -1
+	for i, l := range b.lines {
+		if l.textStart < l.textStop && (i > 0 || !b.block) && !directiveRe.Match(src[l.textStart:l.textStop]) {
+			if n := l.indent(src); indent < 0 || n < indent {
+				indent = n
+			}
+		}
+	}
+
+	var out []paragraph
+	var current []commentLine
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		parts := make([]string, len(current))
+		for i, l := range current {
+			parts[i] = string(src[l.textStart:l.textStop])
+		}
+		out = append(out, paragraph{lines: current, text: joinLines(parts)})
+		current = nil
+	}
+	for i, l := range b.lines {
+		preformatted := (i > 0 || !b.block) && l.indent(src) > indent
+		if l.textStart >= l.textStop || preformatted || directiveRe.Match(src[l.textStart:l.textStop]) {
+			flush()
+			continue
+		}
+		current = append(current, l)
+	}
+	flush()
+	return out
+}
+
+// render formats translated text for the paragraph's position. Continuation
+// lines reuse the prefix of the paragraph's second line, or of its only line
+// when that line is not the opening line of a block comment.
+func (b commentBlock) render(src []byte, p paragraph, translated string) string {
+	translated = joinLines(strings.Fields(translated))
+	if !b.wrap || len(b.lines) < 2 {
+		return translated
+	}
+
+	contPrefix, ok := b.continuationPrefix(src, p)
+	if !ok {
+		return translated
+	}
+
+	limit := 0
+	for _, l := range b.lines {
+		limit = max(limit, displayWidth(string(src[l.lineStart:l.textStop])))
+	}
+	firstPrefix := displayWidth(string(src[p.lines[0].lineStart:p.lines[0].textStart]))
+	textWidth := limit - max(firstPrefix, displayWidth(contPrefix))
+	if textWidth < 20 {
+		return translated
+	}
+	return strings.Join(wrapText(translated, textWidth), "\n"+contPrefix)
+}
+
+// continuationPrefix returns the prefix for wrapped lines of p: that of its
+// second line, of its only line, or, when p opens a block comment, of the
+// first non-blank line below it.
+func (b commentBlock) continuationPrefix(src []byte, p paragraph) (string, bool) {
+	prefix := func(l commentLine) string { return string(src[l.lineStart:l.textStart]) }
+	switch {
+	case len(p.lines) > 1:
+		return prefix(p.lines[1]), true
+	case !b.block || p.lines[0] != b.lines[0]:
+		return prefix(p.lines[0]), true
+	}
+	for _, l := range b.lines[1:] {
+		if l.textStart < l.textStop {
+			return prefix(l), true
+		}
+	}
+	return "", false
+}
+
+// joinLines joins wrapped comment lines, without a space between CJK text.
+func joinLines(parts []string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(b.String())
+			next, _ := utf8.DecodeRuneInString(part)
+			if !isWide(prev) || !isWide(next) {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// wrapText breaks text into lines of at most limit display columns, at
+// spaces or between wide (CJK) characters.
+func wrapText(text string, limit int) []string {
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	for _, word := range splitWords(text) {
+		w := displayWidth(word)
+		if lineWidth+w > limit && line.Len() > 0 {
+			lines = append(lines, strings.TrimRight(line.String(), " "))
+			line.Reset()
+			word = strings.TrimLeft(word, " ")
+			w = displayWidth(word)
+			lineWidth = 0
+		}
+		line.WriteString(word)
+		lineWidth += w
+	}
+	if line.Len() > 0 {
+		lines = append(lines, strings.TrimRight(line.String(), " "))
+	}
+	return lines
+}
+
+// splitWords splits text into break opportunities: each wide character on
+// its own, and narrow words together with their leading space.
+func splitWords(text string) []string {
+	var words []string
+	var cur strings.Builder
+	for _, r := range text {
+		switch {
+		case isWide(r):
+			if cur.Len() > 0 {
+				words = append(words, cur.String())
+				cur.Reset()
+			}
+			words = append(words, string(r))
+		case r == ' ':
+			if cur.Len() > 0 && strings.TrimSpace(cur.String()) != "" {
+				words = append(words, cur.String())
+				cur.Reset()
+			}
+			cur.WriteRune(r)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r == '\t':
+			n += 4
+		case isWide(r):
+			n += 2
+		case unicode.IsPrint(r):
+			n++
+		}
+	}
+	return n
+}
+
+func isWide(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
+
+// lineIndex holds the offset at which each line of a file starts.
+type lineIndex []int
+
+func newLineIndex(src []byte) lineIndex {
+	idx := lineIndex{0}
+	for i, c := range src {
+		if c == '\n' {
+			idx = append(idx, i+1)
+		}
+	}
+	return idx
+}
+
+// number returns the line of the byte at pos, counting from 0.
+func (idx lineIndex) number(pos int) int {
+	return sort.Search(len(idx), func(i int) bool { return idx[i] > pos }) - 1
+}
+
+// start returns the offset of the start of the line of the byte at pos.
+func (idx lineIndex) start(pos int) int {
+	return idx[idx.number(pos)]
+}
+
+func skipBlank(src []byte, pos, stop int) int {
+	for pos < stop && (src[pos] == ' ' || src[pos] == '\t') {
+		pos++
+	}
+	return pos
+}
+
+func trimRightBlank(src []byte, start, stop int) int {
+	for stop > start && isSpace(src[stop-1]) {
+		stop--
+	}
+	return stop
+}
@@ -0,0 +1,73 @@
+package code
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+)
+
+// goSpans lexes Go source with go/scanner. The comment group right before
+// `import "C"` is cgo preamble, which is C code, and is left out.
+func goSpans(src []byte) []span {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s scanner.Scanner
+	s.Init(file, src, nil, scanner.ScanComments)
+
+	var spans []span
+	groupStart := 0
+	importAt := -1
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		offset := file.Offset(pos)
+		switch tok {
+		case token.COMMENT:
+			spans = append(spans, goComment(src, offset))
+			continue
+		case token.IMPORT:
+			importAt = groupStart
+		case token.STRING:
+			if importAt >= 0 && lit == `"C"` {
+				spans = spans[:importAt]
+			}
+			spans = append(spans, goString(src, offset, lit))
+		}
+		if tok != token.IMPORT {
+			importAt = -1
+		}
+		if tok != token.SEMICOLON || lit != "\n" {
+			groupStart = len(spans)
+		}
+	}
+	return spans
+}
+
+func goComment(src []byte, offset int) span {
+	if bytes.HasPrefix(src[offset:], []byte("//")) {
+		end := len(src)
+		if n := bytes.IndexByte(src[offset:], '\n'); n >= 0 {
+			end = offset + n
+		}
+		return span{kind: spanLineComment, start: offset, stop: end, textStart: offset + 2, textStop: end, open: "//"}
+	}
+	end := len(src)
+	if n := bytes.Index(src[offset+2:], []byte("*/")); n >= 0 {
+		end = offset + 2 + n
+	}
+	return span{kind: spanBlockComment, start: offset, stop: min(end+2, len(src)), textStart: offset + 2, textStop: end, open: "/*", close: "*/"}
+}
+
+func goString(src []byte, offset int, lit string) span {
+	quote := lit[:1]
+	end := offset + len(lit)
+	if quote == "`" {
+		// Carriage returns are dropped from raw string literals.
+		if n := bytes.IndexByte(src[offset+1:], '`'); n >= 0 {
+			end = offset + 2 + n
+		}
+	}
+	return span{kind: spanString, start: offset, stop: end, textStart: offset + 1, textStop: end - 1, open: quote, close: quote}
+}
@@ -0,0 +1,453 @@
+package code
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type spanKind int
+
+const (
+	spanLineComment spanKind = iota
+	spanBlockComment
+	spanString
+	// spanDocstring is a string literal standing alone on its lines, such as
+	// a Python docstring. It is translated like a block comment.
+	spanDocstring
+)
+
+// span is a comment or string literal. start/stop cover the whole token,
+// textStart/textStop the part between the delimiters.
+type span struct {
+	kind      spanKind
+	start     int
+	stop      int
+	textStart int
+	textStop  int
+	open      string
+	close     string
+}
+
+type quoteRule struct {
+	open      string
+	close     string
+	escape    bool
+	multiline bool
+	// char limits the literal to one character or escape, as in Rust, where
+	// a quote that does not close right away starts a lifetime such as 'a.
+	char bool
+}
+
+type syntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	quotes        []quoteRule
+	// hashAtWordStart requires "#" comments to start a word, as in shells.
+	hashAtWordStart bool
+	// docstrings marks standalone multi-line strings as documentation.
+	docstrings bool
+	// regexLiterals skips JavaScript regular expression literals, which may
+	// hold "//" or "/*".
+	regexLiterals bool
+	// longBrackets reads Lua long brackets, [[...]] or [==[...]==], as
+	// strings, and as comments after "--".
+	longBrackets bool
+}
+
+var (
+	cQuotes = []quoteRule{
+		{open: `"`, close: `"`, escape: true},
+		{open: `'`, close: `'`, escape: true},
+	}
+	hashQuotes = []quoteRule{
+		{open: `"`, close: `"`, escape: true},
+		{open: `'`, close: `'`},
+	}
+
+	cSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	}
+	goSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: append([]quoteRule{
+			{open: "`", close: "`", multiline: true},
+		}, cQuotes...),
+	}
+	rustSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: []quoteRule{
+			{open: `"`, close: `"`, escape: true},
+			{open: `'`, close: `'`, char: true},
+		},
+	}
+	jsSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: append([]quoteRule{
+			{open: "`", close: "`", escape: true, multiline: true},
+		}, cQuotes...),
+		regexLiterals: true,
+	}
+	tripleQuoteSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes: append([]quoteRule{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+		}, cQuotes...),
+	}
+	pythonSyntax = syntax{
+		lineComments: []string{"#"},
+		docstrings:   true,
+		quotes: append([]quoteRule{
+			{open: `"""`, close: `"""`, escape: true, multiline: true},
+			{open: `'''`, close: `'''`, escape: true, multiline: true},
+		}, cQuotes...),
+	}
+	rubySyntax = syntax{
+		lineComments: []string{"#"},
+		quotes:       cQuotes,
+	}
+	shellSyntax = syntax{
+		lineComments:    []string{"#"},
+		quotes:          hashQuotes,
+		hashAtWordStart: true,
+	}
+	sqlSyntax = syntax{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        hashQuotes,
+	}
+	luaSyntax = syntax{
+		lineComments: []string{"--"},
+		quotes:       cQuotes,
+		longBrackets: true,
+	}
+	haskellSyntax = syntax{
+		lineComments:  []string{"--"},
+		blockComments: [][2]string{{"{-", "-}"}},
+		quotes:        []quoteRule{{open: `"`, close: `"`, escape: true}},
+	}
+	markupSyntax = syntax{
+		blockComments: [][2]string{{"<!--", "-->"}},
+	}
+	cssSyntax = syntax{
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	}
+	scssSyntax = syntax{
+		lineComments:  []string{"//"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        cQuotes,
+	}
+	phpSyntax = syntax{
+		lineComments:  []string{"//", "#"},
+		blockComments: [][2]string{{"/*", "*/"}},
+		quotes:        hashQuotes,
+	}
+)
+
+var languages = map[string]syntax{
+	"go":         goSyntax,
+	"c":          cSyntax,
+	"h":          cSyntax,
+	"cpp":        cSyntax,
+	"c++":        cSyntax,
+	"cc":         cSyntax,
+	"hpp":        cSyntax,
+	"java":       cSyntax,
+	"cs":         cSyntax,
+	"csharp":     cSyntax,
+	"rust":       rustSyntax,
+	"rs":         rustSyntax,
+	"dart":       cSyntax,
+	"kotlin":     tripleQuoteSyntax,
+	"kt":         tripleQuoteSyntax,
+	"swift":      tripleQuoteSyntax,
+	"scala":      tripleQuoteSyntax,
+	"js":         jsSyntax,
+	"javascript": jsSyntax,
+	"jsx":        jsSyntax,
+	"mjs":        jsSyntax,
+	"ts":         jsSyntax,
+	"typescript": jsSyntax,
+	"tsx":        jsSyntax,
+	"python":     pythonSyntax,
+	"py":         pythonSyntax,
+	"ruby":       rubySyntax,
+	"rb":         rubySyntax,
+	"sh":         shellSyntax,
+	"bash":       shellSyntax,
+	"zsh":        shellSyntax,
+	"shell":      shellSyntax,
+	"dockerfile": shellSyntax,
+	"make":       shellSyntax,
+	"makefile":   shellSyntax,
+	"yaml":       shellSyntax,
+	"yml":        shellSyntax,
+	"toml":       shellSyntax,
+	"r":          shellSyntax,
+	"perl":       shellSyntax,
+	"pl":         shellSyntax,
+	"sql":        sqlSyntax,
+	"lua":        luaSyntax,
+	"haskell":    haskellSyntax,
+	"hs":         haskellSyntax,
+	"html":       markupSyntax,
+	"xml":        markupSyntax,
+	"svg":        markupSyntax,
+	"css":        cssSyntax,
+	"scss":       scssSyntax,
+	"less":       scssSyntax,
+	"php":        phpSyntax,
+}
+
+// LanguageFromInfo returns the language named by a Markdown fence info
+// string such as "python" or "js title=app.js", or "" when unknown.
+func LanguageFromInfo(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	name := strings.ToLower(strings.Trim(fields[0], "{}."))
+	if _, ok := languages[name]; !ok {
+		return ""
+	}
+	return name
+}
+
+func lex(lang string, src []byte) []span {
+	syn, ok := languages[lang]
+	if !ok {
+		return nil
+	}
+	if lang == "go" {
+		return goSpans(src)
+	}
+
+	type token struct {
+		text  string
+		apply func(i int) (span, bool)
+		// skip consumes the token without recording a span.
+		skip bool
+	}
+	var tokens []token
+	for _, bc := range syn.blockComments {
+		bc := bc
+		tokens = append(tokens, token{text: bc[0], apply: func(i int) (span, bool) {
+			return scanDelimited(src, i, bc[0], bc[1], false, true, spanBlockComment)
+		}})
+	}
+	for _, marker := range syn.lineComments {
+		marker := marker
+		tokens = append(tokens, token{text: marker, apply: func(i int) (span, bool) {
+			if marker == "#" && syn.hashAtWordStart && i > 0 && !isSpace(src[i-1]) {
+				return span{}, false
+			}
+			end := len(src)
+			if n := bytes.IndexByte(src[i:], '\n'); n >= 0 {
+				end = i + n
+			}
+			return span{kind: spanLineComment, start: i, stop: end, textStart: i + len(marker), textStop: end, open: marker}, true
+		}})
+	}
+	for _, q := range syn.quotes {
+		q := q
+		tokens = append(tokens, token{text: q.open, apply: func(i int) (span, bool) {
+			if q.char {
+				return scanChar(src, i)
+			}
+			return scanDelimited(src, i, q.open, q.close, q.escape, q.multiline, spanString)
+		}})
+	}
+	if syn.regexLiterals {
+		tokens = append(tokens, token{text: "/", skip: true, apply: func(i int) (span, bool) {
+			return scanRegex(src, i)
+		}})
+	}
+	if syn.longBrackets {
+		tokens = append(tokens,
+			token{text: "--[", apply: func(i int) (span, bool) {
+				return scanLongBracket(src, i, len("--"), spanBlockComment)
+			}},
+			token{text: "[", apply: func(i int) (span, bool) {
+				return scanLongBracket(src, i, 0, spanString)
+			}},
+		)
+	}
+	// Longer delimiters win ("--[" over "--", `"""` over `"`).
+	sort.SliceStable(tokens, func(a, b int) bool { return len(tokens[a].text) > len(tokens[b].text) })
+
+	var spans []span
+	for i := 0; i < len(src); {
+		matched := false
+		for _, tok := range tokens {
+			if !bytes.HasPrefix(src[i:], []byte(tok.text)) {
+				continue
+			}
+			sp, ok := tok.apply(i)
+			if !ok {
+				continue
+			}
+			if !tok.skip {
+				spans = append(spans, sp)
+			}
+			i = sp.stop
+			matched = true
+			break
+		}
+		if !matched {
+			i++
+		}
+	}
+	if syn.docstrings {
+		markDocstrings(src, spans, syn)
+	}
+	return spans
+}
+
+// markDocstrings turns multi-line string literals that stand alone on their
+// lines into docstring spans.
+func markDocstrings(src []byte, spans []span, syn syntax) {
+	lines := newLineIndex(src)
+	for i, sp := range spans {
+		if sp.kind != spanString || !isMultilineQuote(syn, sp.open) {
+			continue
+		}
+		before := src[lines.start(sp.start):sp.start]
+		after := src[sp.stop:]
+		if n := bytes.IndexByte(after, '\n'); n >= 0 {
+			after = after[:n]
+		}
+		if len(bytes.TrimSpace(before)) == 0 && len(bytes.TrimSpace(after)) == 0 {
+			spans[i].kind = spanDocstring
+		}
+	}
+}
+
+func isMultilineQuote(syn syntax, open string) bool {
+	for _, q := range syn.quotes {
+		if q.open == open {
+			return q.multiline
+		}
+	}
+	return false
+}
+
+func scanDelimited(src []byte, i int, open, close string, escape, multiline bool, kind spanKind) (span, bool) {
+	for j := i + len(open); j < len(src); j++ {
+		switch {
+		case escape && src[j] == '\\':
+			j++
+		case src[j] == '\n' && !multiline:
+			return span{}, false
+		case bytes.HasPrefix(src[j:], []byte(close)):
+			return span{kind: kind, start: i, stop: j + len(close), textStart: i + len(open), textStop: j, open: open, close: close}, true
+		}
+	}
+	return span{}, false
+}
+
+// scanChar scans a character literal such as 'a' or '\n'. A quote that is
+// not closed right after one character or escape is not a literal.
+func scanChar(src []byte, i int) (span, bool) {
+	j := i + 1
+	if j >= len(src) {
+		return span{}, false
+	}
+	if src[j] == '\\' {
+		// Escapes such as \' or \u{1F600} end at the first quote after the
+		// escaped character.
+		if j+2 > len(src) {
+			return span{}, false
+		}
+		n := bytes.IndexByte(src[j+2:min(len(src), j+12)], '\'')
+		if n < 0 {
+			return span{}, false
+		}
+		j += 2 + n
+	} else {
+		_, size := utf8.DecodeRune(src[j:])
+		j += size
+	}
+	if j >= len(src) || src[j] != '\'' {
+		return span{}, false
+	}
+	return span{kind: spanString, start: i, stop: j + 1, textStart: i + 1, textStop: j, open: "'", close: "'"}, true
+}
+
+// regexKeywords can precede a regular expression literal.
+var regexKeywords = []string{"return", "typeof", "case", "do", "else", "in", "of", "yield", "await", "void", "delete", "throw", "new"}
+
+// scanRegex scans a JavaScript regular expression literal at src[i]. Like
+// most highlighters, it takes a "/" for the start of one after an operator,
+// an opening bracket, a keyword or at the start of the input, and for a
+// division elsewhere.
+func scanRegex(src []byte, i int) (span, bool) {
+	k := i - 1
+	for k >= 0 && isSpace(src[k]) {
+		k--
+	}
+	if k >= 0 && !strings.ContainsRune("(,=:[!&|?{};+-*%~^", rune(src[k])) && !endsWithKeyword(src[:k+1]) {
+		return span{}, false
+	}
+	class := false
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case '\n':
+			return span{}, false
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if !class {
+				return span{start: i, stop: j + 1}, true
+			}
+		}
+	}
+	return span{}, false
+}
+
+func endsWithKeyword(src []byte) bool {
+	for _, kw := range regexKeywords {
+		if !bytes.HasSuffix(src, []byte(kw)) {
+			continue
+		}
+		if n := len(src) - len(kw); n == 0 || !isWordByte(src[n-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// scanLongBracket scans a Lua long bracket, "[" followed by any number of
+// "=" and another "[", that starts after a prefix of n bytes.
+func scanLongBracket(src []byte, i, n int, kind spanKind) (span, bool) {
+	j := i + n + 1
+	level := 0
+	for j < len(src) && src[j] == '=' {
+		j++
+		level++
+	}
+	if j >= len(src) || src[j] != '[' {
+		return span{}, false
+	}
+	open := string(src[i : j+1])
+	return scanDelimited(src, i, open, "]"+strings.Repeat("=", level)+"]", false, true, kind)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
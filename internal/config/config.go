@@ -20,6 +20,12 @@ type File struct {
 	Endpoint             string `json:"endpoint"`
 	PassphraseTTLSeconds int    `json:"passphrase_ttl_seconds"`
 	PDFFont              string `json:"pdf_font"`
+	PDFFontBold          string `json:"pdf_font_bold,omitempty"`
+	PDFFontItalic        string `json:"pdf_font_italic,omitempty"`
+	PDFFontBoldItalic    string `json:"pdf_font_bold_italic,omitempty"`
+	// PDFFonts are fallback fonts for characters the PDF font lacks, in
+	// order of preference.
+	PDFFonts []string `json:"pdf_fonts,omitempty"`
 }
 
 func ConfigDir() (string, error) {
@@ -46,6 +52,15 @@ func DefaultPDFFontPath() (string, error) {
 	return filepath.Join(dir, "fonts", "LINESeedJP-Regular.ttf"), nil
 }
 
+// DefaultPDFBoldFontPath is the bold companion of DefaultPDFFontPath.
+func DefaultPDFBoldFontPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "fonts", "LINESeedJP-Bold.ttf"), nil
+}
+
 func Load() (File, error) {
 	path, err := ConfigPath()
 	if err != nil {
@@ -1,6 +1,12 @@
 package lang
 
-import "strings"
+import (
+	"strings"
+	"sync"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
 
 const fallbackLang = "en"
 
@@ -28,3 +34,72 @@ func DefaultTargetLang(env string) string {
 	}
 	return trimmed
 }
+
+// rtlLangs are the languages written right to left, by code and English
+// name.
+var rtlLangs = map[string]bool{
+	"ar": true, "arabic": true,
+	"he": true, "iw": true, "hebrew": true,
+	"fa": true, "persian": true, "farsi": true,
+	"ur": true, "urdu": true,
+	"yi": true, "yiddish": true,
+	"ps": true, "pashto": true,
+	"sd": true, "sindhi": true,
+	"ug": true, "uyghur": true,
+	"dv": true, "dhivehi": true,
+	"ckb": true,
+}
+
+// IsRTL reports whether lang, a language code such as "ar" or "he-IL" or an
+// English language name, is written right to left.
+func IsRTL(lang string) bool {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "_-"); i >= 0 {
+		lang = lang[:i]
+	}
+	return rtlLangs[lang]
+}
+
+// Tag returns lang, a language code such as "ja" or "pt_BR.UTF-8" or an
+// English language name such as "Japanese", as a BCP 47 tag. ok is false
+// when lang names no known language.
+func Tag(lang string) (string, bool) {
+	lang = strings.TrimSpace(lang)
+	if i := strings.IndexAny(lang, ".@"); i >= 0 {
+		lang = lang[:i]
+	}
+	if tag, err := language.Parse(strings.ReplaceAll(lang, "_", "-")); err == nil && tag != language.Und {
+		return tag.String(), true
+	}
+	tag, ok := languageNames()[strings.ToLower(lang)]
+	return tag, ok
+}
+
+var (
+	namesOnce sync.Once
+	names     map[string]string
+)
+
+// languageNames maps the lower-case English names of the languages with a
+// two-letter code to that code.
+func languageNames() map[string]string {
+	namesOnce.Do(func() {
+		names = map[string]string{}
+		for a := 'a'; a <= 'z'; a++ {
+			for b := 'a'; b <= 'z'; b++ {
+				base, err := language.ParseBase(string([]rune{a, b}))
+				if err != nil {
+					continue
+				}
+				tag, err := language.Compose(base)
+				if err != nil {
+					continue
+				}
+				if name := display.English.Languages().Name(tag); name != "" {
+					names[strings.ToLower(name)] = tag.String()
+				}
+			}
+		}
+	})
+	return names
+}
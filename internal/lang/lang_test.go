@@ -25,3 +25,38 @@ func TestDefaultTargetLang(t *testing.T) {
 		})
 	}
 }
+
+func TestIsRTL(t *testing.T) {
+	for _, code := range []string{"ar", "he-IL", "fa_IR", "Arabic", " ur "} {
+		if !IsRTL(code) {
+			t.Errorf("IsRTL(%q) = false", code)
+		}
+	}
+	for _, code := range []string{"en", "ja", "", "arm"} {
+		if IsRTL(code) {
+			t.Errorf("IsRTL(%q) = true", code)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	cases := map[string]string{
+		"ja":          "ja",
+		"ja_JP.UTF-8": "ja-JP",
+		"pt-br":       "pt-BR",
+		"zh-Hant":     "zh-Hant",
+		"Japanese":    "ja",
+		" german ":    "de",
+		"iw":          "he",
+	}
+	for in, want := range cases {
+		if got, ok := Tag(in); !ok || got != want {
+			t.Errorf("Tag(%q) = %q, %v, want %q", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "C", "POSIX", "Klingonese", "xx"} {
+		if got, ok := Tag(in); ok {
+			t.Errorf("Tag(%q) = %q", in, got)
+		}
+	}
+}
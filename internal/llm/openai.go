@@ -145,8 +145,11 @@ func buildSystemPrompt(from, to, format string) string {
 
 	format = strings.ToLower(strings.TrimSpace(format))
 	suffix := "Output only the translated text. Do not include any analysis or commentary."
-	if format == "markdown" {
+	switch format {
+	case "markdown":
 		suffix = "Preserve Markdown formatting and output only the translated text. Do not include any analysis or commentary."
+	case "tagged":
+		suffix = "The text contains numbered placeholder tags such as <b1>...</b1> or <code2/>. Keep every tag exactly as written, wrap the corresponding translated words with paired tags, and output only the translated text. Do not include any analysis or commentary."
 	}
 
 	return fmt.Sprintf("You are a translation engine. Translate from %s to %s. %s", src, to, suffix)
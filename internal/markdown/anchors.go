@@ -0,0 +1,188 @@
+package markdown
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Heading anchor modes for WithHeadingAnchors.
+const (
+	// AnchorsAttr appends a `{#slug}` attribute with the original slug.
+	AnchorsAttr = "attr"
+	// AnchorsHTML appends an empty `<a id="slug"></a>` with the original slug.
+	AnchorsHTML = "html"
+	// AnchorsRewrite rewrites in-document fragment links to the slugs of the
+	// translated headings.
+	AnchorsRewrite = "rewrite"
+)
+
+// heading is a heading unit with the GitHub-style slug of its original text.
+type heading struct {
+	unit int
+	// start and stop cover the heading text, without markers or a closing
+	// sequence.
+	start int
+	stop  int
+	slug  string
+	// explicit is set when the heading already carries its own anchor.
+	explicit bool
+}
+
+var explicitAnchorRe = regexp.MustCompile(`(\{#[^}]*\}\s*$)|(?i)<a\s+(id|name)=`)
+
+// slugger builds GitHub-compatible heading slugs, numbering duplicates.
+type slugger map[string]int
+
+func (s slugger) slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ':
+			b.WriteByte('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r):
+			b.WriteRune(r)
+		}
+	}
+	slug := b.String()
+	n, seen := s[slug]
+	s[slug] = n + 1
+	if seen {
+		return slug + "-" + strconv.Itoa(n)
+	}
+	return slug
+}
+
+// collectHeadings returns headings in document order. units maps block
+// nodes to their translation unit index.
+func collectHeadings(src []byte, doc ast.Node, units map[ast.Node]int) []heading {
+	slugs := slugger{}
+	var headings []heading
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		h, ok := n.(*ast.Heading)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		unit, ok := units[h]
+		lines := h.Lines()
+		if !ok || lines.Len() == 0 {
+			return ast.WalkSkipChildren, nil
+		}
+		start, stop := lines.At(0).Start, lines.At(lines.Len()-1).Stop
+		headings = append(headings, heading{
+			unit:     unit,
+			start:    start,
+			stop:     stop,
+			slug:     slugs.slug(plainText(src, h)),
+			explicit: explicitAnchorRe.Match(src[start:stop]),
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return headings
+}
+
+// plainText returns the text of n as a reader sees it, without markup.
+func plainText(src []byte, n ast.Node) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch v := c.(type) {
+		case *ast.Text:
+			value := v.Segment.Value(src)
+			b.Write(util.UnescapePunctuations(util.ResolveNumericReferences(util.ResolveEntityNames(value))))
+			if v.SoftLineBreak() || v.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(v.Value)
+		case *ast.AutoLink:
+			b.Write(v.Label(src))
+			return ast.WalkSkipChildren, nil
+		case *mathInline:
+			b.Write(v.Segment.Value(src))
+			return ast.WalkSkipChildren, nil
+		case *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// anchorEdit returns an insertion that pins the original slug on h.
+func anchorEdit(h heading, mode string) (textSegment, bool) {
+	if h.explicit || h.slug == "" {
+		return textSegment{}, false
+	}
+	switch mode {
+	case AnchorsAttr:
+		return textSegment{start: h.stop, stop: h.stop, text: " {#" + h.slug + "}"}, true
+	case AnchorsHTML:
+		return textSegment{start: h.stop, stop: h.stop, text: fmt.Sprintf(` <a id="%s"></a>`, h.slug)}, true
+	}
+	return textSegment{}, false
+}
+
+// translatedSlugs maps original heading slugs to the slugs of their
+// translations, given the edits made so far.
+func translatedSlugs(body []byte, headings []heading, edits []textSegment) map[string]string {
+	slugs := slugger{}
+	out := map[string]string{}
+	for _, h := range headings {
+		var local []textSegment
+		for _, e := range edits {
+			if e.start >= h.start && e.stop <= h.stop {
+				local = append(local, textSegment{start: e.start - h.start, stop: e.stop - h.start, text: e.text})
+			}
+		}
+		translated := applyEdits(body[h.start:h.stop], local)
+		root, _ := parseDocument(translated)
+		slug := slugs.slug(plainText(translated, root))
+		if slug != h.slug && !h.explicit {
+			out[h.slug] = slug
+		}
+	}
+	return out
+}
+
+var fragmentDestRe = regexp.MustCompile(`^(\]\(\s*<?#)([^)\s>]+)`)
+
+// rewriteFragments points in-document links of b at the new slugs.
+func rewriteFragments(b *inlineBlock, slugs map[string]string) {
+	for id, ph := range b.placeholders {
+		if ph.name != "link" {
+			continue
+		}
+		m := fragmentDestRe.FindStringSubmatchIndex(ph.close)
+		if m == nil {
+			continue
+		}
+		slug, ok := lookupFragment(slugs, ph.close[m[4]:m[5]])
+		if !ok {
+			continue
+		}
+		ph.close = ph.close[:m[4]] + slug + ph.close[m[5]:]
+		b.placeholders[id] = ph
+	}
+}
+
+// lookupFragment finds the new slug for a link fragment, which may be
+// percent-encoded.
+func lookupFragment(slugs map[string]string, fragment string) (string, bool) {
+	if decoded, err := url.PathUnescape(fragment); err == nil {
+		fragment = decoded
+	}
+	slug, ok := slugs[strings.ToLower(fragment)]
+	return slug, ok
+}
@@ -0,0 +1,62 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHeadingAnchorsPinned(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"Getting Started":           "はじめに",
+		"Install <code1/>":          "<code1/> のインストール",
+		"See <link1>setup</link1>.": "<link1>設定</link1>を参照。",
+	}}
+	input := "# Getting Started\n\n## Install `tool` ##\n\n## Custom {#keep}\n\nSee [setup](#getting-started).\n"
+	got, err := Translate(context.Background(), tr, []byte(input), "en", "ja", WithHeadingAnchors(AnchorsAttr))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "# はじめに {#getting-started}\n\n## `tool` のインストール {#install-tool} ##\n\n## Custom {#keep}\n\n[設定](#getting-started)を参照。\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+
+	got, err = Translate(context.Background(), tr, []byte("Getting Started\n===\n"), "en", "ja", WithHeadingAnchors(AnchorsHTML))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if string(got) != "はじめに <a id=\"getting-started\"></a>\n===\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestHeadingAnchorsRewriteLinks(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"Installation": "インストール",
+		"Usage":        "使い方",
+		"See <link1>install</link1> and <link2>usage</link2>.": "<link1>インストール</link1>と<link2>使い方</link2>を参照。",
+	}}
+	input := "See [install](#installation) and [usage][u].\n\n## Installation\n\n## Usage\n\n[u]: #usage\n"
+	got, err := Translate(context.Background(), tr, []byte(input), "en", "ja", WithHeadingAnchors(AnchorsRewrite))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "[インストール](#インストール)と[使い方][u]を参照。\n\n## インストール\n\n## 使い方\n\n[u]: #使い方\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestSlugger(t *testing.T) {
+	s := slugger{}
+	for _, tc := range []struct{ in, want string }{
+		{"Hello, World!", "hello-world"},
+		{"Hello, World!", "hello-world-1"},
+		{"API v2.0 (beta)", "api-v20-beta"},
+		{"日本語 の見出し", "日本語-の見出し"},
+	} {
+		if got := s.slug(tc.in); got != tc.want {
+			t.Fatalf("slug(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,390 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+// Bilingual layouts for WithBilingual.
+const (
+	// BilingualInterleave places each translated block after its original.
+	BilingualInterleave = "interleave"
+	// BilingualTable renders originals and translations as the two columns
+	// of an HTML table, one row per block.
+	BilingualTable = "table"
+)
+
+// topBlock is the line range of a top-level block. Literal blocks (code
+// and math) are emitted once, with any translated comments; nested holds
+// the line ranges of literal blocks inside other blocks, such as code in a
+// list item, which are also emitted once. Footnote definitions are emitted
+// once, so that their label stays unique, with the translation added as a
+// paragraph of the definition.
+type topBlock struct {
+	start    int
+	stop     int
+	literal  bool
+	footnote bool
+	nested   [][2]int
+}
+
+// shift returns b moved by offset bytes.
+func (b topBlock) shift(offset int) topBlock {
+	nested := make([][2]int, len(b.nested))
+	for i, r := range b.nested {
+		nested[i] = [2]int{r[0] + offset, r[1] + offset}
+	}
+	return topBlock{start: b.start + offset, stop: b.stop + offset, literal: b.literal, footnote: b.footnote, nested: nested}
+}
+
+// topLevelBlocks returns the line ranges of the document's top-level
+// blocks. Footnote definitions count as top-level blocks at their source
+// position. Blocks without source lines, such as thematic breaks, are left
+// in the gaps between ranges.
+func topLevelBlocks(src []byte, doc ast.Node) []topBlock {
+	var nodes []ast.Node
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if _, ok := n.(*east.FootnoteList); ok {
+			for fn := n.FirstChild(); fn != nil; fn = fn.NextSibling() {
+				nodes = append(nodes, fn)
+			}
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+
+	var blocks []topBlock
+	for _, n := range nodes {
+		start, stop, ok := blockRange(src, n)
+		if !ok {
+			continue
+		}
+		_, footnote := n.(*east.Footnote)
+		block := topBlock{start: start, stop: stop, literal: isLiteralBlock(n), footnote: footnote}
+		if !block.literal {
+			block.nested = nestedLiterals(src, n)
+		}
+		blocks = append(blocks, block)
+	}
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].start < blocks[j].start })
+	return blocks
+}
+
+func isLiteralBlock(n ast.Node) bool {
+	switch n.(type) {
+	case *ast.CodeBlock, *ast.FencedCodeBlock, *mathBlock:
+		return true
+	}
+	return false
+}
+
+// nestedLiterals returns the line ranges of the literal blocks inside n
+// that start their lines, after any container prefix, so that they can be
+// cut out of a copy of n.
+func nestedLiterals(src []byte, n ast.Node) [][2]int {
+	var ranges [][2]int
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || c == n || !isLiteralBlock(c) {
+			return ast.WalkContinue, nil
+		}
+		if start, stop, ok := blockRange(src, c); ok && isBlankPrefix(src[start:lineContentStart(src, c, start)]) {
+			ranges = append(ranges, [2]int{start, stop})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return ranges
+}
+
+// lineContentStart returns where the first line of the literal block c,
+// which starts at the line start start, begins after its prefix.
+func lineContentStart(src []byte, c ast.Node, start int) int {
+	if lines := c.Lines(); lines.Len() > 0 && lineStartOf(src, lines.At(0).Start) == start {
+		return lines.At(0).Start
+	}
+	end := lineEnd(src, start)
+	trimmed := bytes.TrimLeft(src[start:end], " \t>")
+	return end - len(trimmed)
+}
+
+// isBlankPrefix reports whether a line prefix holds only indentation and
+// blockquote markers.
+func isBlankPrefix(prefix []byte) bool {
+	return len(bytes.Trim(prefix, " \t>")) == 0
+}
+
+// blockRange returns the whole lines covered by n, including fence and
+// underline lines that carry no segment.
+func blockRange(src []byte, n ast.Node) (int, int, bool) {
+	start, stop := -1, -1
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if _, ok := c.(*admonition); ok && !entering && stop >= 0 {
+			// The closing fence follows the last child.
+			stop = closingLine(src, stop, true, ":::")
+		}
+		if !entering || c.Type() != ast.TypeBlock {
+			return ast.WalkContinue, nil
+		}
+		first, last := -1, -1
+		if lines := c.Lines(); lines.Len() > 0 {
+			first, last = lines.At(0).Start, lines.At(lines.Len()-1).Stop
+		}
+		switch v := c.(type) {
+		case *ast.FencedCodeBlock:
+			if v.Info != nil {
+				if first < 0 {
+					last = v.Info.Segment.Stop
+				}
+				first = v.Info.Segment.Start
+			}
+			if last >= 0 {
+				last = closingLine(src, last, false, "```", "~~~")
+			}
+		case *ast.HTMLBlock:
+			if v.HasClosure() {
+				last = v.ClosureLine.Stop
+			}
+		case *ast.Heading:
+			if first >= 0 && !strings.HasPrefix(strings.TrimLeft(string(src[lineStartOf(src, first):first]), " \t>"), "#") &&
+				!strings.HasPrefix(string(src[first:]), "#") {
+				// Setext heading: include the underline.
+				last = closingLine(src, last, false, "=", "-")
+			}
+		}
+		if first >= 0 && (start < 0 || first < start) {
+			start = first
+		}
+		if last > stop {
+			stop = last
+		}
+		return ast.WalkContinue, nil
+	})
+	if start < 0 {
+		return 0, 0, false
+	}
+	start = lineStartOf(src, start)
+	switch v := n.(type) {
+	case *ast.FencedCodeBlock:
+		// The fence line carries no segment when there is no info string.
+		if v.Info == nil {
+			start = openingLine(src, start, "```", "~~~")
+		}
+	case *admonition:
+		start = openingLine(src, start, ":::")
+	}
+	return start, lineEnd(src, stop-1), true
+}
+
+// openingLine searches backwards from the line before pos for a line that
+// starts with one of markers, returning its start or pos when none is found.
+func openingLine(src []byte, pos int, markers ...string) int {
+	for end := pos - 1; end >= 0; {
+		start := lineStartOf(src, end)
+		if hasMarker(src[start:end], markers) {
+			return start
+		}
+		end = start - 1
+	}
+	return pos
+}
+
+// closingLine returns the end of the line after the one that ends at stop
+// when it starts with one of markers, skipping blank lines if skipBlank is
+// set, and stop otherwise.
+func closingLine(src []byte, stop int, skipBlank bool, markers ...string) int {
+	for start := lineEnd(src, stop-1); start < len(src); {
+		end := lineEnd(src, start)
+		line := src[start:end]
+		if hasMarker(line, markers) {
+			return end
+		}
+		if !skipBlank || len(bytes.TrimSpace(line)) > 0 {
+			break
+		}
+		start = end
+	}
+	return stop
+}
+
+func hasMarker(line []byte, markers []string) bool {
+	trimmed := strings.TrimLeft(string(line), " \t>")
+	for _, m := range markers {
+		if strings.HasPrefix(trimmed, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func lineStartOf(src []byte, pos int) int {
+	return bytes.LastIndexByte(src[:pos], '\n') + 1
+}
+
+// lineEnd returns the offset just after the newline ending the line that
+// holds pos.
+func lineEnd(src []byte, pos int) int {
+	pos = max(pos, 0)
+	if n := bytes.IndexByte(src[pos:], '\n'); n >= 0 {
+		return pos + n + 1
+	}
+	return len(src)
+}
+
+// bilingual lays out input and its translation block by block. Blocks
+// without edits, literal blocks and the text between blocks appear once.
+// With rtl, translated blocks are marked as right-to-left text.
+func bilingual(input []byte, edits []textSegment, blocks []topBlock, layout string, rtl bool) []byte {
+	var out bytes.Buffer
+	translated := func(start, stop int, last bool) []byte {
+		var local []textSegment
+		for _, e := range edits {
+			if e.start >= start && (e.start < stop || last && e.start == stop) {
+				local = append(local, textSegment{start: e.start - start, stop: e.stop - start, text: e.text})
+			}
+		}
+		return applyEdits(input[start:stop], local)
+	}
+	changed := func(b topBlock) bool {
+		for _, e := range edits {
+			if e.start >= b.start && e.start < b.stop {
+				return true
+			}
+		}
+		return false
+	}
+
+	// copies returns the original and translated copies of a block: the
+	// original with its nested literal blocks translated, and the
+	// translation without them and the blank lines before them.
+	copies := func(b topBlock) ([]byte, []byte) {
+		var original, result []byte
+		pos := b.start
+		for _, r := range b.nested {
+			original = append(original, input[pos:r[0]]...)
+			original = append(original, translated(r[0], r[1], false)...)
+			result = trimBlankLines(append(result, translated(pos, r[0], false)...))
+			pos = r[1]
+		}
+		original = append(original, input[pos:b.stop]...)
+		result = append(result, translated(pos, b.stop, false)...)
+		return original, result
+	}
+
+	table := layout == BilingualTable
+	pos := 0
+	for i, b := range blocks {
+		gap := translated(pos, b.start, false)
+		switch {
+		case !table:
+			out.Write(gap)
+		case i == 0:
+			out.Write(gap)
+			out.WriteString("<table>\n")
+		case len(bytes.TrimSpace(gap)) > 0:
+			writeRow(&out, false, gap)
+		}
+		pos = b.stop
+
+		if b.literal || !changed(b) {
+			once := translated(b.start, b.stop, false)
+			if table {
+				writeRow(&out, false, once)
+			} else {
+				out.Write(once)
+			}
+			continue
+		}
+		if b.footnote {
+			if both, ok := footnoteBoth(input[b.start:b.stop], translated(b.start, b.stop, false)); ok {
+				if table {
+					writeRow(&out, false, both)
+				} else {
+					out.Write(both)
+				}
+				continue
+			}
+		}
+		original, result := copies(b)
+		if table {
+			writeRow(&out, rtl, original, result)
+			continue
+		}
+		out.Write(original)
+		if !bytes.HasSuffix(original, []byte("\n")) {
+			out.WriteByte('\n')
+		}
+		out.WriteByte('\n')
+		if rtl {
+			result = rtlBlock(result)
+		}
+		out.Write(result)
+	}
+	rest := translated(pos, len(input), true)
+	if table && len(blocks) > 0 {
+		if len(bytes.TrimSpace(rest)) > 0 {
+			writeRow(&out, false, rest)
+		}
+		out.WriteString("</table>\n")
+		return out.Bytes()
+	}
+	out.Write(rest)
+	return out.Bytes()
+}
+
+// footnoteLabel matches the label that opens a footnote definition.
+var footnoteLabel = regexp.MustCompile(`^\[\^[^\]]+\]:[ \t]*`)
+
+// footnoteBoth returns a footnote definition holding the original text and
+// then the translation, indented as a paragraph of the same definition.
+func footnoteBoth(original, result []byte) ([]byte, bool) {
+	label := footnoteLabel.Find(result)
+	if label == nil {
+		return nil, false
+	}
+	out := append([]byte(nil), bytes.TrimRight(original, "\n")...)
+	out = append(out, "\n\n    "...)
+	out = append(out, result[len(label):]...)
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out, true
+}
+
+// trimBlankLines removes the blank lines, which may hold container
+// prefixes, at the end of md.
+func trimBlankLines(md []byte) []byte {
+	for len(md) > 0 {
+		body := bytes.TrimSuffix(md, []byte("\n"))
+		start := bytes.LastIndexByte(body, '\n') + 1
+		if !isBlankPrefix(body[start:]) {
+			return md
+		}
+		md = md[:start]
+	}
+	return md
+}
+
+// writeRow writes a table row with one cell per column, or a single cell
+// spanning both columns. Blank lines around cell content let Markdown
+// renderers parse it as Markdown. With rtl, the second column is marked as
+// right-to-left text.
+func writeRow(out *bytes.Buffer, rtl bool, cells ...[]byte) {
+	out.WriteString("<tr>\n")
+	for i, cell := range cells {
+		switch {
+		case len(cells) == 1:
+			out.WriteString("<td colspan=\"2\">\n\n")
+		case rtl && i == 1:
+			out.WriteString("<td dir=\"rtl\">\n\n")
+		default:
+			out.WriteString("<td>\n\n")
+		}
+		out.Write(bytes.TrimRight(cell, "\n"))
+		out.WriteString("\n\n</td>\n")
+	}
+	out.WriteString("</tr>\n")
+}
@@ -0,0 +1,84 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBilingualInterleave(t *testing.T) {
+	input := "---\ntitle: Doc\n---\n# Title\n\nFirst paragraph\nwraps here.\n\n```go\n// comment\nx := 1\n```\n\n- one\n- two\n\n---\n\nLast[^1]\n\n[^1]: Note\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithBilingual(BilingualInterleave))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "---\ntitle: Doc\n---\n# Title\n\n# TITLE\n\nFirst paragraph\nwraps here.\n\nFIRST PARAGRAPH WRAPS HERE.\n\n```go\n// comment\nx := 1\n```\n\n- one\n- two\n\n- ONE\n- TWO\n\n---\n\nLast[^1]\n\nLAST[^1]\n\n[^1]: Note\n\n    NOTE\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestBilingualTable(t *testing.T) {
+	input := "Hello\n\n~~~\ncode\n~~~\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithBilingual(BilingualTable))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "<table>\n<tr>\n<td>\n\nHello\n\n</td>\n<td>\n\nHELLO\n\n</td>\n</tr>\n<tr>\n<td colspan=\"2\">\n\n~~~\ncode\n~~~\n\n</td>\n</tr>\n</table>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestBilingualInterleaveFencesAndUnderlines(t *testing.T) {
+	input := "Title\n=====\n\n:::note\nBody\n\n:::\n\n- item\n\n  ```\n  code\n  ```\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithBilingual(BilingualInterleave))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "Title\n=====\n\nTITLE\n=====\n\n:::note\nBody\n\n:::\n\n:::note\nBODY\n\n:::\n\n- item\n\n  ```\n  code\n  ```\n\n- ITEM\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestBilingualNestedCodeOnce(t *testing.T) {
+	input := "> Quote\n>\n> ```sh\n> # Install it\n> ```\n>\n> More\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithBilingual(BilingualInterleave), WithCodeComments(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "> Quote\n>\n> ```sh\n> # INSTALL IT\n> ```\n>\n> More\n\n> QUOTE\n>\n> MORE\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestRTLTargetWrapsTranslation(t *testing.T) {
+	input := "---\ntitle: Doc\n---\n# Title\n\nBody\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ar")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "---\ntitle: Doc\n---\n<div dir=\"rtl\">\n\n# TITLE\n\nBODY\n\n</div>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+
+	got, err = Translate(context.Background(), upperTranslator{}, []byte("Hello\n"), "en", "he", WithBilingual(BilingualTable))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want = "<table>\n<tr>\n<td>\n\nHello\n\n</td>\n<td dir=\"rtl\">\n\nHELLO\n\n</td>\n</tr>\n</table>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+
+	got, err = Translate(context.Background(), upperTranslator{}, []byte("Hello\n"), "en", "he", WithBilingual(BilingualInterleave))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want = "Hello\n\n<div dir=\"rtl\">\n\nHELLO\n\n</div>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,267 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// The syntax below is only recognised so that keywords and formulas are kept
+// out of translation; nothing here is ever rendered to HTML.
+
+var (
+	kindAdmonition = ast.NewNodeKind("Admonition")
+	kindMathBlock  = ast.NewNodeKind("MathBlock")
+	kindMathInline = ast.NewNodeKind("MathInline")
+)
+
+// admonition is a `:::note` ... `:::` container. Its body is regular
+// Markdown, and so is the title after the keyword, as in `:::note Title` or
+// `:::note[Title]`; the fences and the keyword are never translated.
+type admonition struct {
+	ast.BaseBlock
+	fence int
+	title text.Segment
+}
+
+func (n *admonition) Kind() ast.NodeKind { return kindAdmonition }
+
+func (n *admonition) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type mathBlock struct {
+	ast.BaseBlock
+	closed bool
+}
+
+func (n *mathBlock) Kind() ast.NodeKind { return kindMathBlock }
+
+func (n *mathBlock) IsRaw() bool { return true }
+
+func (n *mathBlock) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type mathInline struct {
+	ast.BaseInline
+	Segment text.Segment
+}
+
+func (n *mathInline) Kind() ast.NodeKind { return kindMathInline }
+
+func (n *mathInline) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type admonitionParser struct{}
+
+func (p *admonitionParser) Trigger() []byte { return []byte{':'} }
+
+func (p *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	fence := countRun(line[pos:], ':')
+	if fence < 3 || util.IsBlank(line[pos+fence:]) {
+		return nil, parser.NoChildren
+	}
+	node := &admonition{fence: fence}
+	if start, stop := admonitionTitle(line, pos+fence); start < stop {
+		offset := segment.Start - segment.Padding
+		node.title = text.NewSegment(offset+start, offset+stop)
+	}
+	reader.Advance(segment.Len() - 1)
+	return node, parser.HasChildren
+}
+
+// admonitionTitle returns the range of the title in the fence line, after
+// the keyword that starts at pos: in brackets right after the keyword, or
+// the rest of the line.
+func admonitionTitle(line []byte, pos int) (int, int) {
+	line = util.TrimRightSpace(line)
+	end := pos
+	for end < len(line) && !util.IsSpace(line[end]) && line[end] != '[' {
+		end++
+	}
+	if end < len(line) && line[end] == '[' {
+		if closing := bytes.LastIndexByte(line, ']'); closing > end {
+			return end + 1, closing
+		}
+		return 0, 0
+	}
+	for end < len(line) && util.IsSpace(line[end]) {
+		end++
+	}
+	return end, len(line)
+}
+
+func (p *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	trimmed := util.TrimLeftSpace(util.TrimRightSpace(line))
+	if n := countRun(trimmed, ':'); n >= node.(*admonition).fence && n == len(trimmed) {
+		reader.Advance(segment.Len() - 1)
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *admonitionParser) CanInterruptParagraph() bool { return true }
+
+func (p *admonitionParser) CanAcceptIndentedLine() bool { return false }
+
+// mathBlockParser recognises display math delimited by `$$` lines. Like a
+// paragraph, display math does not span blank lines: a `$$` line without a
+// closing `$$` before the next blank line is left as text.
+type mathBlockParser struct{}
+
+func (p *mathBlockParser) Trigger() []byte { return []byte{'$'} }
+
+func (p *mathBlockParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 || !bytes.HasPrefix(line[pos:], []byte("$$")) {
+		return nil, parser.NoChildren
+	}
+	node := &mathBlock{}
+	rest := util.TrimRightSpace(line[pos+2:])
+	node.closed = bytes.HasSuffix(rest, []byte("$$"))
+	if !node.closed && !mathClosedAhead(reader) {
+		return nil, parser.NoChildren
+	}
+	node.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	return node, parser.NoChildren
+}
+
+func (p *mathBlockParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	m := node.(*mathBlock)
+	if m.closed {
+		return parser.Close
+	}
+	line, segment := reader.PeekLine()
+	m.Lines().Append(segment)
+	reader.Advance(segment.Len() - 1)
+	if bytes.HasSuffix(util.TrimRightSpace(line), []byte("$$")) {
+		return parser.Close
+	}
+	return parser.Continue | parser.NoChildren
+}
+
+func (p *mathBlockParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+// mathClosedAhead reports whether a line ending in `$$` follows the current
+// one before a blank line or the end of the input.
+func mathClosedAhead(reader text.Reader) bool {
+	line, segment := reader.Position()
+	defer reader.SetPosition(line, segment)
+	for {
+		reader.AdvanceLine()
+		next, _ := reader.PeekLine()
+		if next == nil || util.IsBlank(next) {
+			return false
+		}
+		if bytes.HasSuffix(util.TrimRightSpace(next), []byte("$$")) {
+			return true
+		}
+	}
+}
+
+func (p *mathBlockParser) CanInterruptParagraph() bool { return false }
+
+func (p *mathBlockParser) CanAcceptIndentedLine() bool { return false }
+
+// mathInlineParser recognises `$...$` and `$$...$$` on a single line. Like
+// pandoc, the opening `$` must not be followed by a space and the closing
+// one must not be preceded by a space or followed by a digit, so prices such
+// as "$5 and $10" stay text.
+type mathInlineParser struct{}
+
+func (p *mathInlineParser) Trigger() []byte { return []byte{'$'} }
+
+func (p *mathInlineParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	delim := countRun(line, '$')
+	if delim > 2 || delim >= len(line) || util.IsSpace(line[delim]) {
+		return nil
+	}
+	for i := delim + 1; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if line[i] != '$' || util.IsSpace(line[i-1]) {
+			continue
+		}
+		if countRun(line[i:], '$') != delim {
+			continue
+		}
+		end := i + delim
+		if delim == 1 && end < len(line) && line[end] >= '0' && line[end] <= '9' {
+			continue
+		}
+		block.Advance(end)
+		return &mathInline{Segment: text.NewSegment(segment.Start, segment.Start+end)}
+	}
+	return nil
+}
+
+// admonitionTitles returns the titles of the admonitions in doc.
+func admonitionTitles(input []byte, doc ast.Node) []textSegment {
+	var titles []textSegment
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if a, ok := n.(*admonition); ok && entering && a.title.Len() > 0 {
+			titles = append(titles, textSegment{start: a.title.Start, stop: a.title.Stop, text: string(a.title.Value(input))})
+		}
+		return ast.WalkContinue, nil
+	})
+	return titles
+}
+
+func countRun(b []byte, c byte) int {
+	n := 0
+	for n < len(b) && b[n] == c {
+		n++
+	}
+	return n
+}
+
+type extraSyntax struct{}
+
+func (extraSyntax) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&admonitionParser{}, 150),
+			util.Prioritized(&mathBlockParser{}, 150),
+		),
+		parser.WithInlineParsers(
+			util.Prioritized(&mathInlineParser{}, 150),
+		),
+	)
+}
+
+var alertMarkerRe = regexp.MustCompile(`^\[![A-Za-z]+\][ \t]*\r?\n`)
+
+// alertMarkerEnd returns the offset of the newline that ends a GitHub alert
+// marker (`> [!NOTE]`) at the start of n, or -1.
+func alertMarkerEnd(src []byte, n ast.Node) int {
+	if _, ok := n.(*ast.Paragraph); !ok {
+		return -1
+	}
+	if _, ok := n.Parent().(*ast.Blockquote); !ok || n.PreviousSibling() != nil {
+		return -1
+	}
+	lines := n.Lines()
+	if lines.Len() < 2 {
+		return -1
+	}
+	first := lines.At(0)
+	m := alertMarkerRe.Find(first.Value(src))
+	if m == nil {
+		return -1
+	}
+	return first.Start + bytes.IndexByte(m, '\n')
+}
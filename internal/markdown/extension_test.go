@@ -0,0 +1,42 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTranslateExtendedSyntax(t *testing.T) {
+	input := "Energy is $E = mc^2$ for $5 and $10.[^1]\n\n" +
+		"$$\n\\sum_i x_i\n$$\n\n" +
+		":::note Custom title\nAdmonition body\n:::\n\n" +
+		":::tip[Bracket title]\nTip body\n:::\n\n" +
+		"> [!WARNING]\n> Alert body\n\n" +
+		"Term\n: Definition text\n\n" +
+		"[^1]: Footnote text.\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "ENERGY IS $E = mc^2$ FOR $5 AND $10.[^1]\n\n" +
+		"$$\n\\sum_i x_i\n$$\n\n" +
+		":::note CUSTOM TITLE\nADMONITION BODY\n:::\n\n" +
+		":::tip[BRACKET TITLE]\nTIP BODY\n:::\n\n" +
+		"> [!WARNING]\n> ALERT BODY\n\n" +
+		"TERM\n: DEFINITION TEXT\n\n" +
+		"[^1]: FOOTNOTE TEXT.\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestMathBlockNeedsClosingFence(t *testing.T) {
+	input := "Costs in\n$$ dollars\n\n$$\nUnclosed math\n\nLast paragraph\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "COSTS IN $$ DOLLARS\n\n$$ UNCLOSED MATH\n\nLAST PARAGRAPH\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
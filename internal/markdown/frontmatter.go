@@ -0,0 +1,376 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	frontMatterYAML = "yaml"
+	frontMatterTOML = "toml"
+)
+
+type frontMatterStyle int
+
+const (
+	stylePlain frontMatterStyle = iota
+	styleDoubleQuoted
+	styleSingleQuoted
+	styleLiteralBlock
+	styleFoldedBlock
+)
+
+// frontMatterField is a translatable string value. start/stop cover the
+// value token (including quotes) inside the front matter bytes.
+type frontMatterField struct {
+	key    string
+	start  int
+	stop   int
+	value  string
+	style  frontMatterStyle
+	indent string
+}
+
+var (
+	yamlKeyRe = regexp.MustCompile(`^([A-Za-z0-9_.-]+)[ \t]*:[ \t]*`)
+	tomlKeyRe = regexp.MustCompile(`^([A-Za-z0-9_-]+)[ \t]*=[ \t]*`)
+	// yamlNonStringRe matches plain scalars that YAML reads as something
+	// other than a string: null, booleans (including the YAML 1.1 forms),
+	// numbers and dates.
+	yamlNonStringRe = regexp.MustCompile(`^(?:(?i:~|null|true|false|yes|no|on|off|y|n)` +
+		`|[-+]?(?:[0-9][0-9_]*(?::[0-5]?[0-9])*(?:\.[0-9_]*)?|\.[0-9][0-9_]*)(?:[eE][-+]?[0-9]+)?` +
+		`|[-+]?0(?:x[0-9a-fA-F_]+|o[0-7_]+|b[01_]+)|[-+]?\.(?i:inf)|\.(?i:nan)` +
+		`|[0-9]{4}-[0-9]{1,2}-[0-9]{1,2}(?:[Tt ].*)?)$`)
+)
+
+// splitFrontMatter returns the length of a leading YAML (---) or TOML (+++)
+// front matter block, including its closing delimiter line.
+func splitFrontMatter(input []byte) (int, string) {
+	first, rest, ok := cutLine(input)
+	if !ok {
+		return 0, ""
+	}
+	var format string
+	switch strings.TrimRight(string(first), " \t\r") {
+	case "---":
+		format = frontMatterYAML
+	case "+++":
+		format = frontMatterTOML
+	default:
+		return 0, ""
+	}
+
+	offset := len(input) - len(rest)
+	for len(rest) > 0 {
+		line, next, _ := cutLine(rest)
+		trimmed := strings.TrimRight(string(line), " \t\r")
+		if (format == frontMatterYAML && (trimmed == "---" || trimmed == "...")) ||
+			(format == frontMatterTOML && trimmed == "+++") {
+			return offset + len(rest) - len(next), format
+		}
+		offset += len(rest) - len(next)
+		rest = next
+	}
+	return 0, ""
+}
+
+// cutLine splits off the first line. The returned line excludes the newline,
+// and ok reports whether a newline was found.
+func cutLine(b []byte) (line, rest []byte, ok bool) {
+	i := bytes.IndexByte(b, '\n')
+	if i < 0 {
+		return b, nil, false
+	}
+	return b[:i], b[i+1:], true
+}
+
+func frontMatterFields(fm []byte, format string, keys []string) []frontMatterField {
+	if len(keys) == 0 {
+		return nil
+	}
+	wanted := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		wanted[k] = true
+	}
+
+	var fields []frontMatterField
+	lines := lineOffsets(fm)
+	// Skip the opening and closing delimiter lines.
+	for i := 1; i < len(lines)-1; i++ {
+		start, stop := lines[i][0], lines[i][1]
+		line := string(fm[start:stop])
+		if format == frontMatterTOML && strings.HasPrefix(strings.TrimSpace(line), "[") {
+			break
+		}
+		re := yamlKeyRe
+		if format == frontMatterTOML {
+			re = tomlKeyRe
+		}
+		m := re.FindStringSubmatchIndex(line)
+		if m == nil || !wanted[line[m[2]:m[3]]] {
+			continue
+		}
+		key := line[m[2]:m[3]]
+		valueStart := start + m[1]
+		raw := strings.TrimRight(string(fm[valueStart:stop]), " \t\r")
+
+		if format == frontMatterYAML && (strings.HasPrefix(raw, "|") || strings.HasPrefix(raw, ">")) {
+			field, last, ok := yamlBlockField(fm, lines, i, key, raw[0])
+			if ok {
+				fields = append(fields, field)
+				i = last
+			}
+			continue
+		}
+		field, ok := inlineField(raw, format)
+		if !ok {
+			continue
+		}
+		field.key = key
+		field.start += valueStart
+		field.stop += valueStart
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func inlineField(raw, format string) (frontMatterField, bool) {
+	if raw == "" {
+		return frontMatterField{}, false
+	}
+	switch raw[0] {
+	case '"':
+		if strings.HasPrefix(raw, `"""`) {
+			return frontMatterField{}, false
+		}
+		end := closingDoubleQuote(raw)
+		if end < 0 {
+			return frontMatterField{}, false
+		}
+		return frontMatterField{start: 0, stop: end + 1, value: unescapeDoubleQuoted(raw[1:end]), style: styleDoubleQuoted}, true
+	case '\'':
+		if strings.HasPrefix(raw, "'''") {
+			return frontMatterField{}, false
+		}
+		end := closingSingleQuote(raw, format == frontMatterYAML)
+		if end < 0 {
+			return frontMatterField{}, false
+		}
+		value := raw[1:end]
+		if format == frontMatterYAML {
+			value = strings.ReplaceAll(value, "''", "'")
+		}
+		return frontMatterField{start: 0, stop: end + 1, value: value, style: styleSingleQuoted}, true
+	}
+	if format == frontMatterTOML || strings.ContainsAny(raw[:1], "[{&*!%@`") {
+		return frontMatterField{}, false
+	}
+	value := raw
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimRight(value[:i], " \t")
+	}
+	if yamlNonStringRe.MatchString(value) {
+		return frontMatterField{}, false
+	}
+	return frontMatterField{start: 0, stop: len(value), value: value, style: stylePlain}, true
+}
+
+func yamlBlockField(fm []byte, lines [][2]int, header int, key string, indicator byte) (frontMatterField, int, bool) {
+	style := styleLiteralBlock
+	if indicator == '>' {
+		style = styleFoldedBlock
+	}
+
+	first, last := -1, -1
+	indent := ""
+	for j := header + 1; j < len(lines)-1; j++ {
+		line := string(fm[lines[j][0]:lines[j][1]])
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lead := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if lead == "" {
+			break
+		}
+		if first < 0 {
+			first = j
+			indent = lead
+		}
+		last = j
+	}
+	if first < 0 {
+		return frontMatterField{}, header, false
+	}
+
+	var parts []string
+	for j := first; j <= last; j++ {
+		line := string(fm[lines[j][0]:lines[j][1]])
+		parts = append(parts, strings.TrimPrefix(strings.TrimRight(line, "\r"), indent))
+	}
+	sep := "\n"
+	if style == styleFoldedBlock {
+		sep = " "
+	}
+	return frontMatterField{
+		key:    key,
+		start:  lines[first][0] + len(indent),
+		stop:   lines[last][0] + len(strings.TrimRight(string(fm[lines[last][0]:lines[last][1]]), "\r")),
+		value:  strings.Join(parts, sep),
+		style:  style,
+		indent: indent,
+	}, last, true
+}
+
+func lineOffsets(b []byte) [][2]int {
+	var out [][2]int
+	start := 0
+	for i, c := range b {
+		if c == '\n' {
+			out = append(out, [2]int{start, i})
+			start = i + 1
+		}
+	}
+	if start < len(b) {
+		out = append(out, [2]int{start, len(b)})
+	}
+	return out
+}
+
+func closingDoubleQuote(raw string) int {
+	for i := 1; i < len(raw); i++ {
+		switch raw[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+func closingSingleQuote(raw string, doubledEscape bool) int {
+	for i := 1; i < len(raw); i++ {
+		if raw[i] != '\'' {
+			continue
+		}
+		if doubledEscape && i+1 < len(raw) && raw[i+1] == '\'' {
+			i++
+			continue
+		}
+		return i
+	}
+	return -1
+}
+
+// doubleQuotedEscapes maps the single-character escapes of YAML and TOML
+// double-quoted strings to what they stand for.
+var doubleQuotedEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': `"`,
+	'/': "/", '\\': `\`, 'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+// unescapeDoubleQuoted decodes the escapes of a YAML or TOML double-quoted
+// string. Unknown or malformed escapes are kept as they are.
+func unescapeDoubleQuoted(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := s[i+1]
+		if out, ok := doubleQuotedEscapes[c]; ok {
+			b.WriteString(out)
+			i++
+			continue
+		}
+		digits := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+		if digits == 0 || i+2+digits > len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		code, err := strconv.ParseUint(s[i+2:i+2+digits], 16, 32)
+		if err != nil || code > unicode.MaxRune {
+			b.WriteByte(s[i])
+			continue
+		}
+		b.WriteRune(rune(code))
+		i += 1 + digits
+	}
+	return b.String()
+}
+
+// escapeDoubleQuoted escapes s for a double-quoted string, with only the
+// escapes YAML and TOML have in common.
+func escapeDoubleQuoted(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if unicode.IsControl(r) || r == '\u2028' || r == '\u2029' {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+func (f frontMatterField) render(translated, format string) string {
+	switch f.style {
+	case styleLiteralBlock:
+		lines := strings.Split(translated, "\n")
+		return strings.Join(lines, "\n"+f.indent)
+	case styleFoldedBlock:
+		return strings.Join(strings.Fields(translated), " ")
+	case styleSingleQuoted:
+		if format == frontMatterYAML {
+			return "'" + strings.ReplaceAll(singleLine(translated), "'", "''") + "'"
+		}
+		if !strings.ContainsAny(translated, "'\n") {
+			return "'" + translated + "'"
+		}
+	case stylePlain:
+		if !yamlNeedsQuoting(translated) {
+			return translated
+		}
+	}
+	return `"` + escapeDoubleQuoted(translated) + `"`
+}
+
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// yamlNeedsQuoting reports whether s would not be read back as the same
+// string when written as a plain scalar.
+func yamlNeedsQuoting(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || yamlNonStringRe.MatchString(s) {
+		return true
+	}
+	if strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>'\"%@`") {
+		return true
+	}
+	return strings.Contains(s, ": ") || strings.HasSuffix(s, ":") || strings.Contains(s, " #") ||
+		strings.IndexFunc(s, unicode.IsControl) >= 0
+}
@@ -0,0 +1,91 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFrontMatterLeftIntactByDefault(t *testing.T) {
+	input := "---\ntitle: Hello\ntags: [a, b]\n---\n\n# Heading\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "---\ntitle: Hello\ntags: [a, b]\n---\n\n# HEADING\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestFrontMatterYAMLKeys(t *testing.T) {
+	input := "---\ntitle: Hello world # comment\ndescription: \"Say \\\"hi\\\"\"\nsummary: >\n  Folded\n  text\nslug: hello-world\n---\nBody\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja",
+		WithFrontMatterKeys([]string{"title", "description", "summary"}))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "---\ntitle: HELLO WORLD # comment\ndescription: \"SAY \\\"HI\\\"\"\nsummary: >\n  FOLDED TEXT\nslug: hello-world\n---\nBODY\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestFrontMatterTOMLKeys(t *testing.T) {
+	input := "+++\ntitle = 'Hello'\ndraft = false\n\n[params]\ntitle = \"nested\"\n+++\nBody\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja",
+		WithFrontMatterKeys([]string{"title"}))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "+++\ntitle = 'HELLO'\ndraft = false\n\n[params]\ntitle = \"nested\"\n+++\nBODY\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if n := CountChunks([]byte(input), 0, WithFrontMatterKeys([]string{"title"})); n != 2 {
+		t.Fatalf("CountChunks = %d, want 2", n)
+	}
+}
+
+func TestFrontMatterDoubleQuotedEscapes(t *testing.T) {
+	cases := map[string]string{
+		`café \x41\U0001F600`: "café A\U0001F600",
+		`a\/b\ c\_d\0`:        "a/b c d\x00",
+		`tab\there\r\n`:       "tab\there\r\n",
+		`bad \q and \u12`:     `bad \q and \u12`,
+	}
+	for in, want := range cases {
+		if got := unescapeDoubleQuoted(in); got != want {
+			t.Fatalf("unescapeDoubleQuoted(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for _, s := range []string{"say \"hi\"\r\n\\ \x1b ", "plain"} {
+		if got := unescapeDoubleQuoted(escapeDoubleQuoted(s)); got != s {
+			t.Fatalf("round trip of %q gave %q", s, got)
+		}
+	}
+}
+
+func TestFrontMatterPlainScalarsStayStrings(t *testing.T) {
+	for _, s := range []string{"yes", "No", "null", "~", "123", "1.5e3", "0x1F", ".inf", "2024-01-02", "a: b", "Note:", "- item", "x #y"} {
+		if !yamlNeedsQuoting(s) {
+			t.Fatalf("%q written plain", s)
+		}
+	}
+	for _, s := range []string{"Hello world", "yesterday", "123 apples", "a:b"} {
+		if yamlNeedsQuoting(s) {
+			t.Fatalf("%q quoted", s)
+		}
+	}
+
+	words := map[string]string{"Accept": "yes", "Count": "42", "Label": "Key: value"}
+	input := "---\ntitle: Accept\ndescription: Count\nsummary: Label\nweight: 10\n---\n"
+	got, err := Translate(context.Background(), &mapTranslator{m: words}, []byte(input), "en", "ja",
+		WithFrontMatterKeys([]string{"title", "description", "summary", "weight"}))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "---\ntitle: \"yes\"\ndescription: \"42\"\nsummary: \"Key: value\"\nweight: 10\n---\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
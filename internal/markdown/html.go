@@ -0,0 +1,393 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+	"golang.org/x/net/html"
+)
+
+// inlineHTMLTags are elements that stay inside a sentence; any other element
+// ends the current translation run.
+var inlineHTMLTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "br": true,
+	"cite": true, "del": true, "dfn": true, "em": true, "i": true, "img": true,
+	"ins": true, "mark": true, "q": true, "s": true, "small": true, "span": true,
+	"strong": true, "sub": true, "sup": true, "time": true, "u": true, "var": true,
+	"wbr": true,
+}
+
+// literalHTMLTags keep their content byte-for-byte. Inline ones become a
+// single placeholder inside the surrounding sentence.
+var literalHTMLTags = map[string]bool{
+	"code": true, "kbd": true, "samp": true,
+	"pre": true, "script": true, "style": true, "textarea": true, "math": true, "svg": true,
+}
+
+var translatableHTMLAttrs = map[string]bool{
+	"alt": true, "title": true, "placeholder": true, "aria-label": true,
+}
+
+var htmlAttrRe = regexp.MustCompile(`(?i)([\s"'])([a-z][a-z0-9-]*)(\s*=\s*)("[^"]*"|'[^']*')`)
+
+type htmlPiece struct {
+	raw  string
+	tag  string
+	kind html.TokenType
+}
+
+// translateHTML translates text content and user-visible attributes of an
+// HTML fragment, keeping every tag and literal element intact.
+func (s *session) translateHTML(raw string) (string, error) {
+	z := html.NewTokenizer(strings.NewReader(raw))
+	var out strings.Builder
+	var run []htmlPiece
+
+	flush := func() error {
+		translated, err := s.htmlRun(run)
+		if err != nil {
+			return err
+		}
+		out.WriteString(translated)
+		run = run[:0]
+		return nil
+	}
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		tokRaw := string(z.Raw())
+		switch tt {
+		case html.TextToken:
+			run = append(run, htmlPiece{raw: tokRaw, kind: tt})
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			translatedTag, err := s.htmlAttributes(tokRaw)
+			if err != nil {
+				return "", err
+			}
+			if literalHTMLTags[tag] && tt == html.StartTagToken {
+				literal := translatedTag + skipHTMLElement(z, tag)
+				if tag == "code" || tag == "kbd" || tag == "samp" {
+					run = append(run, htmlPiece{raw: literal, tag: tag, kind: html.CommentToken})
+					continue
+				}
+				if err := flush(); err != nil {
+					return "", err
+				}
+				out.WriteString(literal)
+				continue
+			}
+			if inlineHTMLTags[tag] {
+				run = append(run, htmlPiece{raw: translatedTag, tag: tag, kind: tt})
+				continue
+			}
+			if err := flush(); err != nil {
+				return "", err
+			}
+			out.WriteString(translatedTag)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if inlineHTMLTags[string(name)] {
+				run = append(run, htmlPiece{raw: tokRaw, tag: string(name), kind: tt})
+				continue
+			}
+			if err := flush(); err != nil {
+				return "", err
+			}
+			out.WriteString(tokRaw)
+		default:
+			if len(run) > 0 {
+				run = append(run, htmlPiece{raw: tokRaw, tag: "x", kind: html.CommentToken})
+				continue
+			}
+			out.WriteString(tokRaw)
+		}
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// skipHTMLElement consumes tokens up to the matching end tag and returns
+// their raw bytes.
+func skipHTMLElement(z *html.Tokenizer, tag string) string {
+	var b strings.Builder
+	depth := 1
+	for depth > 0 {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		b.Write(z.Raw())
+		name, _ := z.TagName()
+		if string(name) != tag {
+			continue
+		}
+		switch tt {
+		case html.StartTagToken:
+			depth++
+		case html.EndTagToken:
+			depth--
+		}
+	}
+	return b.String()
+}
+
+// htmlRun translates a run of text and inline tags as one sentence, with
+// tags replaced by placeholders. Surrounding whitespace is kept as is.
+func (s *session) htmlRun(run []htmlPiece) (string, error) {
+	var all strings.Builder
+	hasWords := false
+	for _, p := range run {
+		all.WriteString(p.raw)
+		if p.kind == html.TextToken && strings.TrimSpace(html.UnescapeString(p.raw)) != "" {
+			hasWords = true
+		}
+	}
+	if !hasWords {
+		return all.String(), nil
+	}
+
+	joined := all.String()
+	lead := len(joined) - len(strings.TrimLeft(joined, " \t\r\n"))
+	trail := len(joined) - len(strings.TrimRight(joined, " \t\r\n"))
+
+	block := &inlineBlock{placeholders: map[int]placeholder{}, multiline: true}
+	pairs := matchHTMLPairs(run)
+	var src strings.Builder
+	next := 0
+	ids := map[int]int{}
+	for i, p := range run {
+		switch {
+		case p.kind == html.TextToken:
+			src.WriteString(p.raw)
+		case p.kind == html.StartTagToken && pairs[i] > i:
+			next++
+			ids[pairs[i]] = next
+			block.placeholders[next] = placeholder{name: p.tag, kind: placeholderPair, open: p.raw, close: run[pairs[i]].raw}
+			fmt.Fprintf(&src, "<%s%d>", p.tag, next)
+		case p.kind == html.EndTagToken && pairs[i] >= 0 && pairs[i] < i:
+			fmt.Fprintf(&src, "</%s%d>", p.tag, ids[i])
+		default:
+			next++
+			block.placeholders[next] = placeholder{name: p.tag, kind: placeholderAtom, open: p.raw}
+			fmt.Fprintf(&src, "<%s%d/>", p.tag, next)
+		}
+	}
+
+	source := src.String()
+	block.source = source[lead : len(source)-trail]
+	rebuilt, ok, err := s.block(block)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return joined[:lead] + rebuilt + joined[len(joined)-trail:], nil
+	}
+
+	// Fall back to translating each text token on its own.
+	var b strings.Builder
+	for _, p := range run {
+		if p.kind != html.TextToken {
+			b.WriteString(p.raw)
+			continue
+		}
+		out, err := s.spacedText(p.raw)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(out)
+	}
+	return b.String(), nil
+}
+
+// matchHTMLPairs maps each paired start tag to its end tag index and back;
+// unmatched tags map to -1.
+func matchHTMLPairs(run []htmlPiece) []int {
+	pairs := make([]int, len(run))
+	var stack []int
+	for i, p := range run {
+		pairs[i] = -1
+		switch p.kind {
+		case html.StartTagToken:
+			if p.tag != "br" && p.tag != "img" && p.tag != "wbr" {
+				stack = append(stack, i)
+			}
+		case html.EndTagToken:
+			for j := len(stack) - 1; j >= 0; j-- {
+				if run[stack[j]].tag == p.tag {
+					pairs[stack[j]] = i
+					pairs[i] = stack[j]
+					stack = stack[:j]
+					break
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// htmlAttributes translates user-visible attribute values in a raw tag.
+func (s *session) htmlAttributes(tag string) (string, error) {
+	matches := htmlAttrRe.FindAllStringSubmatchIndex(tag, -1)
+	if len(matches) == 0 {
+		return tag, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		name := strings.ToLower(tag[m[4]:m[5]])
+		if !translatableHTMLAttrs[name] {
+			continue
+		}
+		quoted := tag[m[8]:m[9]]
+		value := html.UnescapeString(quoted[1 : len(quoted)-1])
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		out, err := s.text(value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(tag[last : m[8]+1])
+		b.WriteString(escapeHTMLAttr(out, quoted[0]))
+		last = m[9] - 1
+	}
+	b.WriteString(tag[last:])
+	return b.String(), nil
+}
+
+func escapeHTMLAttr(value string, quote byte) string {
+	value = strings.ReplaceAll(value, "&", "&amp;")
+	value = strings.ReplaceAll(value, "<", "&lt;")
+	if quote == '"' {
+		return strings.ReplaceAll(value, `"`, "&quot;")
+	}
+	return strings.ReplaceAll(value, "'", "&#39;")
+}
+
+// spacedText translates text while keeping its leading and trailing
+// whitespace, which carries layout in HTML sources.
+func (s *session) spacedText(text string) (string, error) {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text, nil
+	}
+	out, err := s.text(trimmed)
+	if err != nil {
+		return "", err
+	}
+	i := strings.Index(text, trimmed)
+	return text[:i] + out + text[i+len(trimmed):], nil
+}
+
+// htmlBlocks returns the lines of the HTML blocks of doc, including those
+// nested in list items and blockquotes.
+func htmlBlocks(doc ast.Node) []blockLines {
+	var blocks []blockLines
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		block, ok := n.(*ast.HTMLBlock)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		var closure *text.Segment
+		if block.HasClosure() {
+			closure = &block.ClosureLine
+		}
+		if lines, ok := linesOf(block.Lines(), closure); ok {
+			blocks = append(blocks, lines)
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+// blockLines is the content of a raw block, one segment per line. Inside a
+// container each line follows a prefix, such as list indentation or "> ",
+// which is not part of the content and is kept when the block is rewritten.
+type blockLines []text.Segment
+
+// linesOf returns lines, plus an optional closure line, as blockLines.
+func linesOf(lines *text.Segments, closure *text.Segment) (blockLines, bool) {
+	if lines.Len() == 0 {
+		return nil, false
+	}
+	out := append(blockLines(nil), lines.Sliced(0, lines.Len())...)
+	if closure != nil {
+		out = append(out, *closure)
+	}
+	return out, true
+}
+
+// text returns the content of the lines without their prefixes. Columns of
+// a tab that the prefix only partly used are given back as spaces.
+func (b blockLines) text(src []byte) string {
+	var out strings.Builder
+	for _, line := range b {
+		out.WriteString(strings.Repeat(" ", line.Padding))
+		out.Write(line.Value(src))
+	}
+	return out.String()
+}
+
+// edit returns the edit that replaces the lines with out, a rewrite of
+// their text, each line after its original prefix. When out has a different
+// number of lines, every line gets the longest prefix of the block, without
+// trailing blanks on empty lines.
+func (b blockLines) edit(src []byte, out string) textSegment {
+	prefixes := make([]string, len(b))
+	deepest := ""
+	for i := 1; i < len(b); i++ {
+		prefixes[i] = string(src[b[i-1].Stop:b[i].Start])
+		if len(prefixes[i]) > len(deepest) {
+			deepest = prefixes[i]
+		}
+	}
+	lines := strings.SplitAfter(out, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var rebuilt strings.Builder
+	for i, line := range lines {
+		switch {
+		case len(lines) == len(b):
+			rebuilt.WriteString(prefixes[i])
+			line = strings.TrimPrefix(line, strings.Repeat(" ", b[i].Padding))
+		case i == 0:
+		case strings.TrimSpace(line) == "":
+			rebuilt.WriteString(strings.TrimRight(deepest, " \t"))
+		default:
+			rebuilt.WriteString(deepest)
+		}
+		rebuilt.WriteString(line)
+	}
+	return textSegment{start: b[0].Start, stop: b[len(b)-1].Stop, text: rebuilt.String()}
+}
+
+// inlineHTML translates attributes of raw inline HTML atoms in a block.
+func (s *session) inlineHTML(b *inlineBlock) error {
+	ids := b.placeholderIDs(func(ph placeholder) bool {
+		return ph.name == "html" && ph.kind == placeholderAtom
+	})
+	for _, id := range ids {
+		ph := b.placeholders[id]
+		out, err := s.htmlAttributes(ph.open)
+		if err != nil {
+			return err
+		}
+		ph.open = out
+		b.placeholders[id] = ph
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+
+	"github.com/yuin/goldmark/text"
+)
+
+func TestHTMLUntouchedByDefault(t *testing.T) {
+	input := "<p align=\"center\">Hello</p>\n\nText\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if string(got) != "<p align=\"center\">Hello</p>\n\nTEXT\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestHTMLBlocksTranslated(t *testing.T) {
+	input := "<details>\n<summary>Click <b>here</b> to expand</summary>\n\nBody text\n\n</details>\n\n<p align=\"center\">\n  <img src=\"logo.png\" alt=\"Project logo\">\n  <code>keep me</code> and go\n</p>\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithHTML(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "<details>\n<summary>CLICK <b>HERE</b> TO EXPAND</summary>\n\nBODY TEXT\n\n</details>\n\n<p align=\"center\">\n  <img src=\"logo.png\" alt=\"PROJECT LOGO\">\n  <code>keep me</code> AND GO\n</p>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestInlineHTMLAttributes(t *testing.T) {
+	input := "See <abbr title=\"HyperText\">HTML</abbr> docs.\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithHTML(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if string(got) != "SEE <abbr title=\"HYPERTEXT\">HTML</abbr> DOCS.\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func TestNestedHTMLBlocksTranslated(t *testing.T) {
+	input := "- Item\n\n  <p>\n  Nested text\n  </p>\n\n> <div>\n> Quoted text\n> </div>\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithHTML(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "- ITEM\n\n  <p>\n  NESTED TEXT\n  </p>\n\n> <div>\n> QUOTED TEXT\n> </div>\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestBlockLinesEditAddsPrefixes(t *testing.T) {
+	src := []byte("> a\n> b\n")
+	lines := blockLines{text.NewSegment(2, 4), text.NewSegment(6, 8)}
+	if got := lines.text(src); got != "a\nb\n" {
+		t.Fatalf("text = %q", got)
+	}
+	e := lines.edit(src, "A\nX\n\nB\n")
+	if got := string(src[:e.start]) + e.text + string(src[e.stop:]); got != "> A\n> X\n>\n> B\n" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,553 @@
+package markdown
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+)
+
+var errUnsupportedInline = errors.New("unsupported inline markup")
+
+type placeholderKind int
+
+const (
+	placeholderPair placeholderKind = iota
+	placeholderAtom
+)
+
+type placeholder struct {
+	name  string
+	kind  placeholderKind
+	open  string
+	close string
+
+	// markupAt and origMarkup locate the editable markup in the source (the
+	// closing markup of a pair, or the atom itself) so rewritten markup can
+	// also be applied when the block falls back.
+	markupAt   int
+	origMarkup string
+	title      *quotedText
+}
+
+func (ph placeholder) markup() string {
+	if ph.kind == placeholderAtom {
+		return ph.open
+	}
+	return ph.close
+}
+
+// inlineBlock is a leaf block serialized as one translation unit. Inline
+// markup is replaced by numbered placeholder tags so the whole sentence can
+// be translated at once and rebuilt afterwards.
+type inlineBlock struct {
+	start        int
+	stop         int
+	source       string
+	placeholders map[int]placeholder
+	cell         bool
+	// multiline keeps single newlines from the translation; blank lines are
+	// still removed because they would end the enclosing block.
+	multiline bool
+}
+
+var blankLinesRe = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+
+type inlineSerializer struct {
+	src          []byte
+	lines        []int
+	pos          int
+	b            strings.Builder
+	next         int
+	placeholders map[int]placeholder
+}
+
+func serializeBlock(src []byte, n ast.Node) (*inlineBlock, error) {
+	lines := n.Lines()
+	if lines == nil || lines.Len() == 0 {
+		return nil, errUnsupportedInline
+	}
+	s := &inlineSerializer{
+		src:          src,
+		pos:          lines.At(0).Start,
+		placeholders: map[int]placeholder{},
+	}
+	for i := 0; i < lines.Len(); i++ {
+		s.lines = append(s.lines, lines.At(i).Start)
+	}
+
+	child := n.FirstChild()
+	if _, ok := child.(*east.TaskCheckBox); ok {
+		s.skipCheckBox()
+		child = child.NextSibling()
+	}
+	if end := alertMarkerEnd(src, n); end >= 0 {
+		for child != nil && isTextBefore(child, end) {
+			child = child.NextSibling()
+		}
+		s.pos = s.nextLineStart(end)
+	}
+	start := s.pos
+	for ; child != nil; child = child.NextSibling() {
+		if err := s.node(child); err != nil {
+			return nil, err
+		}
+	}
+
+	_, cell := n.(*east.TableCell)
+	return &inlineBlock{
+		start:        start,
+		stop:         s.pos,
+		source:       s.b.String(),
+		placeholders: s.placeholders,
+		cell:         cell,
+	}, nil
+}
+
+func (s *inlineSerializer) node(n ast.Node) error {
+	switch v := n.(type) {
+	case *ast.Text:
+		return s.text(v)
+	case *ast.Emphasis:
+		name := "i"
+		if v.Level >= 2 {
+			name = "b"
+		}
+		return s.pair(n, name, s.delimLen("*_", v.Level), func() int {
+			return s.delimLen("*_", v.Level)
+		})
+	case *east.Strikethrough:
+		return s.pair(n, "s", s.delimLen("~", 2), func() int {
+			return s.delimLen("~", 2)
+		})
+	case *ast.Link:
+		if !s.hasPrefix("[") {
+			return errUnsupportedInline
+		}
+		return s.pair(n, "link", 1, s.linkCloseLen)
+	case *ast.Image:
+		if !s.hasPrefix("![") {
+			return errUnsupportedInline
+		}
+		return s.pair(n, "img", 2, s.linkCloseLen)
+	case *ast.CodeSpan:
+		return s.atom("code", s.codeSpanLen())
+	case *ast.RawHTML:
+		if v.Segments == nil || v.Segments.Len() == 0 || v.Segments.At(0).Start != s.pos {
+			return errUnsupportedInline
+		}
+		return s.atom("html", v.Segments.At(v.Segments.Len()-1).Stop-s.pos)
+	case *ast.AutoLink:
+		size := len(v.Label(s.src))
+		if s.hasPrefix("<") {
+			size += 2
+		}
+		return s.atom("url", size)
+	case *mathInline:
+		if v.Segment.Start != s.pos {
+			return errUnsupportedInline
+		}
+		return s.atom("math", v.Segment.Len())
+	case *east.FootnoteLink:
+		end := strings.IndexByte(string(s.src[s.pos:]), ']')
+		if !s.hasPrefix("[^") || end < 0 {
+			return errUnsupportedInline
+		}
+		return s.atom("fn", end+1)
+	case *east.FootnoteBacklink:
+		// Added by the footnote transformer; it has no source bytes.
+		return nil
+	default:
+		return errUnsupportedInline
+	}
+}
+
+func isTextBefore(n ast.Node, pos int) bool {
+	t, ok := n.(*ast.Text)
+	return ok && t.Segment.Stop <= pos
+}
+
+func (s *inlineSerializer) text(t *ast.Text) error {
+	seg := t.Segment
+	if seg.Start != s.pos || seg.Stop < seg.Start {
+		return errUnsupportedInline
+	}
+	s.b.Write(seg.Value(s.src))
+	s.pos = seg.Stop
+	if !t.SoftLineBreak() && !t.HardLineBreak() {
+		return nil
+	}
+
+	end := s.nextLineStart(s.pos)
+	if end < 0 {
+		return errUnsupportedInline
+	}
+	if t.HardLineBreak() {
+		return s.atom("br", end-s.pos)
+	}
+	s.b.WriteByte(' ')
+	s.pos = end
+	return nil
+}
+
+func (s *inlineSerializer) pair(n ast.Node, name string, openLen int, closeLen func() int) error {
+	if openLen <= 0 || s.pos+openLen > len(s.src) {
+		return errUnsupportedInline
+	}
+	id := s.nextID()
+	open := string(s.src[s.pos : s.pos+openLen])
+	s.pos += openLen
+	contentStart := s.pos
+	fmt.Fprintf(&s.b, "<%s%d>", name, id)
+
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if err := s.node(c); err != nil {
+			return err
+		}
+	}
+
+	size := closeLen()
+	if size <= 0 || s.pos+size > len(s.src) {
+		return errUnsupportedInline
+	}
+	ph := placeholder{
+		name:       name,
+		kind:       placeholderPair,
+		open:       open,
+		close:      string(s.src[s.pos : s.pos+size]),
+		markupAt:   s.pos,
+		origMarkup: string(s.src[s.pos : s.pos+size]),
+	}
+	if name == "link" || name == "img" {
+		label := string(s.src[contentStart:s.pos])
+		ph.close = referenceClose(ph.close, label)
+		if title, ok := findLinkTitle(ph.close); ok {
+			ph.title = &title
+		}
+	}
+	s.pos += size
+	fmt.Fprintf(&s.b, "</%s%d>", name, id)
+	s.placeholders[id] = ph
+	return nil
+}
+
+// referenceClose turns shortcut ("[Foo]") and collapsed ("[Foo][]")
+// references into full references so the reference ID survives once the
+// link text is translated.
+func referenceClose(close, label string) string {
+	if (close != "]" && close != "][]") || strings.ContainsAny(label, "\n]") {
+		return close
+	}
+	return "][" + label + "]"
+}
+
+func (s *inlineSerializer) atom(name string, size int) error {
+	if size <= 0 || s.pos+size > len(s.src) {
+		return errUnsupportedInline
+	}
+	id := s.nextID()
+	raw := string(s.src[s.pos : s.pos+size])
+	s.placeholders[id] = placeholder{name: name, kind: placeholderAtom, open: raw, markupAt: s.pos, origMarkup: raw}
+	s.pos += size
+	fmt.Fprintf(&s.b, "<%s%d/>", name, id)
+	return nil
+}
+
+func (s *inlineSerializer) nextID() int {
+	s.next++
+	return s.next
+}
+
+func (s *inlineSerializer) hasPrefix(prefix string) bool {
+	return strings.HasPrefix(string(s.src[s.pos:]), prefix)
+}
+
+func (s *inlineSerializer) nextLineStart(pos int) int {
+	for _, start := range s.lines {
+		if start > pos {
+			return start
+		}
+	}
+	return -1
+}
+
+func (s *inlineSerializer) skipCheckBox() {
+	if s.hasPrefix("[") && s.pos+3 <= len(s.src) && s.src[s.pos+2] == ']' {
+		s.pos += 3
+	}
+	for s.pos < len(s.src) && (s.src[s.pos] == ' ' || s.src[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+func (s *inlineSerializer) delimLen(chars string, max int) int {
+	if s.pos >= len(s.src) || strings.IndexByte(chars, s.src[s.pos]) < 0 {
+		return 0
+	}
+	return s.runLen(s.pos, s.src[s.pos], max)
+}
+
+func (s *inlineSerializer) runLen(pos int, c byte, max int) int {
+	n := 0
+	for pos+n < len(s.src) && s.src[pos+n] == c && n < max {
+		n++
+	}
+	return n
+}
+
+func (s *inlineSerializer) codeSpanLen() int {
+	ticks := s.runLen(s.pos, '`', len(s.src))
+	if ticks == 0 {
+		return 0
+	}
+	for i := s.pos + ticks; i < len(s.src); {
+		if s.src[i] != '`' {
+			i++
+			continue
+		}
+		run := s.runLen(i, '`', len(s.src))
+		if run == ticks {
+			return i + run - s.pos
+		}
+		i += run
+	}
+	return 0
+}
+
+// linkCloseLen measures "](dest "title")", "][ref]" or a bare "]".
+func (s *inlineSerializer) linkCloseLen() int {
+	src := s.src
+	i := s.pos
+	if i >= len(src) || src[i] != ']' {
+		return 0
+	}
+	i++
+	if i >= len(src) {
+		return 1
+	}
+	switch src[i] {
+	case '(':
+		depth := 0
+		var quote byte
+		for ; i < len(src); i++ {
+			c := src[i]
+			switch {
+			case c == '\\':
+				i++
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+				if depth == 0 {
+					return i + 1 - s.pos
+				}
+			}
+		}
+		return 0
+	case '[':
+		end := strings.IndexByte(string(src[i:]), ']')
+		if end < 0 {
+			return 0
+		}
+		return i + end + 1 - s.pos
+	default:
+		return 1
+	}
+}
+
+// placeholderIDs returns the IDs of matching placeholders in source order.
+func (b *inlineBlock) placeholderIDs(match func(placeholder) bool) []int {
+	ids := make([]int, 0, len(b.placeholders))
+	for id, ph := range b.placeholders {
+		if match(ph) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (b *inlineBlock) format() string {
+	if len(b.placeholders) == 0 {
+		return "text"
+	}
+	return "tagged"
+}
+
+var placeholderTagRe = regexp.MustCompile(`<(/?)([A-Za-z]+)(\d+)(\s*/?)>`)
+
+// chunks splits the source of b into pieces of at most maxChars runes. It
+// only cuts after the end of a sentence outside every placeholder pair, so
+// each piece keeps its tags balanced. ok is false when a sentence is longer
+// than maxChars.
+func (b *inlineBlock) chunks(maxChars int) ([]string, bool) {
+	if fitsChunk(b.source, maxChars) {
+		return []string{b.source}, true
+	}
+	src := b.source
+	tags := placeholderTagRe.FindAllStringSubmatchIndex(src, -1)
+	var sentences []string
+	depth, start := 0, 0
+	for i := 0; i < len(src); {
+		if len(tags) > 0 && tags[0][0] == i {
+			m := tags[0]
+			switch {
+			case strings.TrimSpace(src[m[8]:m[9]]) == "/":
+			case src[m[2]:m[3]] == "/":
+				depth--
+			default:
+				depth++
+			}
+			i, tags = m[1], tags[1:]
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(src[i:])
+		i += size
+		if depth != 0 || !sentenceEnd(r, src[i:]) {
+			continue
+		}
+		for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+			i++
+		}
+		sentences = append(sentences, src[start:i])
+		start = i
+	}
+	if start < len(src) {
+		sentences = append(sentences, src[start:])
+	}
+
+	var chunks []string
+	var current strings.Builder
+	n := 0
+	for _, sentence := range sentences {
+		size := utf8.RuneCountInString(sentence)
+		if size > maxChars {
+			return nil, false
+		}
+		if n+size > maxChars {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			n = 0
+		}
+		current.WriteString(sentence)
+		n += size
+	}
+	return append(chunks, current.String()), true
+}
+
+// sentenceEnd reports whether r ends a sentence when rest follows it: a full
+// stop of CJK text, or ".", "!" or "?" before a blank or the end of the
+// block.
+func sentenceEnd(r rune, rest string) bool {
+	switch r {
+	case '。', '！', '？':
+		return true
+	case '.', '!', '?':
+		return rest == "" || rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n'
+	}
+	return false
+}
+
+// rebuild maps a translated string back to Markdown. It reports false when
+// the placeholders did not come back exactly once and properly nested.
+func (b *inlineBlock) rebuild(translated string) (string, bool) {
+	var out strings.Builder
+	seen := map[int]int{}
+	var stack []int
+	last := 0
+
+	writeText := func(text string) {
+		if b.multiline {
+			text = blankLinesRe.ReplaceAllString(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+		} else {
+			text = strings.ReplaceAll(text, "\r\n", " ")
+			text = strings.ReplaceAll(text, "\n", " ")
+		}
+		if b.cell {
+			text = escapeTablePipes(text)
+		}
+		out.WriteString(text)
+	}
+
+	for _, m := range placeholderTagRe.FindAllStringSubmatchIndex(translated, -1) {
+		closing := translated[m[2]:m[3]] == "/"
+		name := strings.ToLower(translated[m[4]:m[5]])
+		selfClosing := strings.TrimSpace(translated[m[8]:m[9]]) == "/"
+		id, err := strconv.Atoi(translated[m[6]:m[7]])
+		if err != nil {
+			return "", false
+		}
+		ph, ok := b.placeholders[id]
+		if !ok || ph.name != name {
+			return "", false
+		}
+
+		writeText(translated[last:m[0]])
+		last = m[1]
+
+		switch {
+		case ph.kind == placeholderAtom:
+			if closing || seen[id] != 0 {
+				return "", false
+			}
+			seen[id]++
+			out.WriteString(ph.open)
+		case selfClosing:
+			return "", false
+		case !closing:
+			if seen[id] != 0 {
+				return "", false
+			}
+			seen[id]++
+			stack = append(stack, id)
+			out.WriteString(ph.open)
+		default:
+			if len(stack) == 0 || stack[len(stack)-1] != id {
+				return "", false
+			}
+			stack = stack[:len(stack)-1]
+			seen[id]++
+			out.WriteString(ph.close)
+		}
+	}
+	writeText(translated[last:])
+
+	if len(stack) != 0 {
+		return "", false
+	}
+	for id, ph := range b.placeholders {
+		want := 1
+		if ph.kind == placeholderPair {
+			want = 2
+		}
+		if seen[id] != want {
+			return "", false
+		}
+	}
+	return out.String(), true
+}
+
+func escapeTablePipes(text string) string {
+	var b strings.Builder
+	escaped := false
+	for _, r := range text {
+		if r == '|' && !escaped {
+			b.WriteByte('\\')
+		}
+		escaped = r == '\\' && !escaped
+		b.WriteRune(r)
+	}
+	return b.String()
+}
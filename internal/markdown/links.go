@@ -0,0 +1,220 @@
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/util"
+)
+
+// quotedText is a quoted title inside link markup. start/stop cover the text
+// between the quotes.
+type quotedText struct {
+	start int
+	stop  int
+	value string
+	quote byte
+}
+
+func (q quotedText) render(translated string) string {
+	translated = strings.Join(strings.Fields(translated), " ")
+	var b strings.Builder
+	for _, r := range translated {
+		switch {
+		case r == '\\',
+			q.quote == '(' && (r == '(' || r == ')'),
+			q.quote != '(' && r == rune(q.quote):
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// findLinkTitle locates the title in closing link markup such as
+// `](https://example.com "Title")`.
+func findLinkTitle(close string) (quotedText, bool) {
+	if !strings.HasPrefix(close, "](") {
+		return quotedText{}, false
+	}
+	i := skipSpace(close, 2)
+	if i < len(close) && close[i] == '<' {
+		end := strings.IndexByte(close[i:], '>')
+		if end < 0 {
+			return quotedText{}, false
+		}
+		i += end + 1
+	} else {
+		depth := 0
+		for ; i < len(close); i++ {
+			c := close[i]
+			if c == '\\' {
+				i++
+				continue
+			}
+			if c == '(' {
+				depth++
+			}
+			if c == ')' {
+				if depth == 0 {
+					break
+				}
+				depth--
+			}
+			if c == ' ' || c == '\t' || c == '\n' {
+				break
+			}
+		}
+	}
+	return quotedTitleAt(close, skipSpace(close, i))
+}
+
+func quotedTitleAt(s string, i int) (quotedText, bool) {
+	if i >= len(s) {
+		return quotedText{}, false
+	}
+	open := s[i]
+	end := open
+	switch open {
+	case '"', '\'':
+	case '(':
+		end = ')'
+	default:
+		return quotedText{}, false
+	}
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case end:
+			return quotedText{start: i + 1, stop: j, value: unescapePunct(s[i+1 : j]), quote: open}, true
+		}
+	}
+	return quotedText{}, false
+}
+
+func skipSpace(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\n' || s[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+func unescapePunct(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && util.IsPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+var referenceDefRe = regexp.MustCompile(`^[ \t>]*\[((?:\\.|[^\]\\])+)\]:[ \t]*(<[^>]*>|\S+)[ \t]*`)
+
+// referenceDef is a single-line link reference definition.
+type referenceDef struct {
+	title *quotedText
+	// destStart and destStop cover the destination, without angle brackets.
+	destStart int
+	destStop  int
+}
+
+// referenceDefs finds single-line link reference definitions. Labels are
+// never translated.
+func referenceDefs(src []byte, doc ast.Node, pc parser.Context) []referenceDef {
+	labels := map[string]bool{}
+	for _, ref := range pc.References() {
+		labels[util.ToLinkReference(ref.Label())] = true
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+
+	skip := literalBlockRanges(doc)
+	var defs []referenceDef
+	for _, line := range lineOffsets(src) {
+		if inRanges(skip, line[0]) {
+			continue
+		}
+		text := string(src[line[0]:line[1]])
+		m := referenceDefRe.FindStringSubmatchIndex(text)
+		if m == nil || !labels[util.ToLinkReference([]byte(text[m[2]:m[3]]))] {
+			continue
+		}
+		def := referenceDef{destStart: line[0] + m[4], destStop: line[0] + m[5]}
+		if text[m[4]] == '<' {
+			def.destStart++
+			def.destStop--
+		}
+		if title, ok := quotedTitleAt(text, m[1]); ok {
+			title.start += line[0]
+			title.stop += line[0]
+			def.title = &title
+		}
+		defs = append(defs, def)
+	}
+	return defs
+}
+
+// literalBlockRanges returns the byte ranges of code and HTML blocks.
+func literalBlockRanges(doc ast.Node) [][2]int {
+	var ranges [][2]int
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.CodeBlock, *ast.FencedCodeBlock, *ast.HTMLBlock, *mathBlock:
+			lines := n.Lines()
+			if lines.Len() > 0 {
+				ranges = append(ranges, [2]int{lines.At(0).Start, lines.At(lines.Len() - 1).Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return ranges
+}
+
+func inRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// linkTitles translates link and image titles in place, so the rewritten
+// closing markup is used by both rebuild and markupEdits.
+func (s *session) linkTitles(b *inlineBlock) error {
+	ids := b.placeholderIDs(func(ph placeholder) bool {
+		return ph.title != nil && strings.TrimSpace(ph.title.value) != ""
+	})
+	for _, id := range ids {
+		ph := b.placeholders[id]
+		out, err := s.text(ph.title.value)
+		if err != nil {
+			return err
+		}
+		ph.close = ph.close[:ph.title.start] + ph.title.render(out) + ph.close[ph.title.stop:]
+		b.placeholders[id] = ph
+	}
+	return nil
+}
+
+// markupEdits returns rewritten markup as standalone edits, used when the
+// block itself falls back to per-segment translation.
+func (b *inlineBlock) markupEdits() []textSegment {
+	var edits []textSegment
+	for _, ph := range b.placeholders {
+		if markup := ph.markup(); markup != ph.origMarkup {
+			edits = append(edits, textSegment{start: ph.markupAt, stop: ph.markupAt + len(ph.origMarkup), text: markup})
+		}
+	}
+	return edits
+}
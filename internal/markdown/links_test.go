@@ -0,0 +1,29 @@
+package markdown
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTranslateLinkTitlesAndReferences(t *testing.T) {
+	input := "See [the guide](https://example.com/Guide \"Guide title\") and [Docs].\n\n![Logo][] is ours.\n\n[docs]: https://example.com/Docs 'Docs title'\n[logo]: logo.png\n\n```\n[docs]: https://example.com \"code\"\n```\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "SEE [THE GUIDE](https://example.com/Guide \"GUIDE TITLE\") AND [DOCS][Docs].\n\n![LOGO][Logo] IS OURS.\n\n[docs]: https://example.com/Docs 'DOCS TITLE'\n[logo]: logo.png\n\n```\n[docs]: https://example.com \"code\"\n```\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
+
+func TestQuotedTextRenderEscapes(t *testing.T) {
+	q := quotedText{quote: '"'}
+	if got := q.render("say \"hi\""); got != "say \\\"hi\\\"" {
+		t.Fatalf("render = %q", got)
+	}
+	q = quotedText{quote: '('}
+	if got := q.render("a (b)"); got != "a \\(b\\)" {
+		t.Fatalf("render = %q", got)
+	}
+}
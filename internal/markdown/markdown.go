@@ -1,14 +1,22 @@
 package markdown
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/fuba/translate/internal/chunk"
+	"github.com/fuba/translate/internal/code"
+	"github.com/fuba/translate/internal/lang"
 	"github.com/fuba/translate/internal/translate"
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/text"
 )
 
@@ -18,60 +26,364 @@ type textSegment struct {
 	text  string
 }
 
+// translationUnit is one leaf block. Blocks whose inline markup could not be
+// serialized, or whose placeholders do not survive translation, fall back to
+// translating their text nodes one by one.
+type translationUnit struct {
+	block    *inlineBlock
+	segments []textSegment
+}
+
 type ProgressFunc func(text string)
 
-func Translate(ctx context.Context, tr translate.Translator, input []byte, from, to string) ([]byte, error) {
-	return TranslateWithProgress(ctx, tr, input, from, to, 0, nil)
+type Option func(*options)
+
+type options struct {
+	frontMatterKeys []string
+	html            bool
+	codeComments    bool
+	codeStrings     bool
+	headingAnchors  string
+	bilingual       string
+	report          io.Writer
 }
 
-func TranslateWithProgress(ctx context.Context, tr translate.Translator, input []byte, from, to string, maxChars int, progress ProgressFunc) ([]byte, error) {
-	segments := collectTextSegments(input)
-	if len(segments) == 0 {
-		return append([]byte(nil), input...), nil
+// WithFrontMatterKeys selects the front matter string fields to translate.
+// Front matter is left untouched when no keys are given.
+func WithFrontMatterKeys(keys []string) Option {
+	return func(o *options) {
+		o.frontMatterKeys = keys
 	}
+}
 
-	translated := make([]string, len(segments))
-	for i, seg := range segments {
-		if strings.TrimSpace(seg.text) == "" {
-			translated[i] = seg.text
+// WithHTML enables translation of text and user-visible attributes inside
+// HTML blocks and inline HTML.
+func WithHTML(enabled bool) Option {
+	return func(o *options) {
+		o.html = enabled
+	}
+}
+
+// WithCodeComments enables translation of comments inside fenced code
+// blocks whose info string names a known language.
+func WithCodeComments(enabled bool) Option {
+	return func(o *options) {
+		o.codeComments = enabled
+	}
+}
+
+// WithCodeStrings also translates prose-like string literals in those code
+// blocks. It has no effect without WithCodeComments.
+func WithCodeStrings(enabled bool) Option {
+	return func(o *options) {
+		o.codeStrings = enabled
+	}
+}
+
+// WithHeadingAnchors keeps in-document links to translated headings working.
+// mode is AnchorsAttr or AnchorsHTML to pin the original slug on each
+// heading, or AnchorsRewrite to point fragment links at the new slugs.
+func WithHeadingAnchors(mode string) Option {
+	return func(o *options) {
+		o.headingAnchors = mode
+	}
+}
+
+// WithBilingual keeps each original block next to its translation, using
+// BilingualInterleave or BilingualTable.
+func WithBilingual(layout string) Option {
+	return func(o *options) {
+		o.bilingual = layout
+	}
+}
+
+// WithReport writes a line to w for each block that had to be translated
+// text node by text node: one with a sentence longer than maxChars, or one
+// whose placeholders did not survive translation.
+func WithReport(w io.Writer) Option {
+	return func(o *options) {
+		o.report = w
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+func Translate(ctx context.Context, tr translate.Translator, input []byte, from, to string, opts ...Option) ([]byte, error) {
+	return TranslateWithProgress(ctx, tr, input, from, to, 0, nil, opts...)
+}
+
+func TranslateWithProgress(ctx context.Context, tr translate.Translator, input []byte, from, to string, maxChars int, progress ProgressFunc, opts ...Option) ([]byte, error) {
+	s := &session{
+		ctx:      ctx,
+		tr:       tr,
+		from:     from,
+		to:       to,
+		maxChars: maxChars,
+		progress: progress,
+		opts:     newOptions(opts),
+	}
+	return s.translate(input)
+}
+
+// CountChunks returns the number of translation requests TranslateWithProgress
+// would make, by running the same pipeline against a counting translator.
+func CountChunks(input []byte, maxChars int, opts ...Option) int {
+	counter := &countingTranslator{}
+	s := &session{
+		ctx:      context.Background(),
+		tr:       counter,
+		maxChars: maxChars,
+		opts:     newOptions(opts),
+	}
+	if _, err := s.translate(input); err != nil {
+		return 0
+	}
+	return counter.calls
+}
+
+type countingTranslator struct {
+	calls int
+}
+
+func (c *countingTranslator) Translate(ctx context.Context, text, from, to, format string) (string, error) {
+	_ = ctx
+	_ = from
+	_ = to
+	_ = format
+	c.calls++
+	return text, nil
+}
+
+type session struct {
+	ctx      context.Context
+	tr       translate.Translator
+	from     string
+	to       string
+	maxChars int
+	progress ProgressFunc
+	opts     options
+}
+
+func (s *session) translate(input []byte) ([]byte, error) {
+	fmLen, fmFormat := splitFrontMatter(input)
+	fm, body := input[:fmLen], input[fmLen:]
+
+	edits := make([]textSegment, 0, 64)
+	for _, field := range frontMatterFields(fm, fmFormat, s.opts.frontMatterKeys) {
+		if strings.TrimSpace(field.value) == "" {
 			continue
 		}
-		chunks := chunk.Split(seg.text, maxChars)
-		out, err := translateChunks(ctx, tr, chunks, from, to, progress)
+		out, err := s.text(field.value)
 		if err != nil {
 			return nil, err
 		}
-		translated[i] = out
+		edits = append(edits, textSegment{start: field.start, stop: field.stop, text: field.render(out, fmFormat)})
 	}
 
-	out := append([]byte(nil), input...)
-	for i := len(segments) - 1; i >= 0; i-- {
-		seg := segments[i]
-		repl := []byte(translated[i])
-		out = append(out[:seg.start], append(repl, out[seg.stop:]...)...)
+	doc := collectDocument(body)
+	bodyEdits, err := s.translateBody(body, doc)
+	if err != nil {
+		return nil, err
+	}
+	edits = appendShifted(edits, bodyEdits, fmLen)
+	if s.opts.bilingual != "" {
+		blocks := make([]topBlock, len(doc.blocks))
+		for i, b := range doc.blocks {
+			blocks[i] = b.shift(fmLen)
+		}
+		return bilingual(input, edits, blocks, s.opts.bilingual, lang.IsRTL(s.to)), nil
+	}
+	out := applyEdits(input, edits)
+	if lang.IsRTL(s.to) {
+		n, _ := splitFrontMatter(out)
+		out = append(out[:n:n], rtlBlock(out[n:])...)
 	}
 	return out, nil
 }
 
-func CountChunks(input []byte, maxChars int) int {
-	segments := collectTextSegments(input)
-	if len(segments) == 0 {
-		return 0
+// rtlBlock wraps Markdown in a div that sets right-to-left direction, so
+// that renderers align and order the translation correctly. The blank lines
+// inside the div let renderers keep parsing its content as Markdown.
+func rtlBlock(md []byte) []byte {
+	body := bytes.TrimRight(md, "\n")
+	if len(bytes.TrimSpace(body)) == 0 {
+		return md
 	}
-	total := 0
-	for _, seg := range segments {
+	out := make([]byte, 0, len(body)+32)
+	out = append(out, "<div dir=\"rtl\">\n\n"...)
+	out = append(out, body...)
+	out = append(out, "\n\n</div>\n"...)
+	return out
+}
+
+func (s *session) translateBody(body []byte, doc document) ([]textSegment, error) {
+	edits := make([]textSegment, 0, len(doc.units))
+	done := make([]bool, len(doc.units))
+	var slugs map[string]string
+	if s.opts.headingAnchors == AnchorsRewrite {
+		// Headings go first so links elsewhere can use their new slugs.
+		for _, h := range doc.headings {
+			unitEdits, err := s.unit(doc.units[h.unit])
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, unitEdits...)
+			done[h.unit] = true
+		}
+		slugs = translatedSlugs(body, doc.headings, edits)
+	} else {
+		for _, h := range doc.headings {
+			if e, ok := anchorEdit(h, s.opts.headingAnchors); ok {
+				edits = append(edits, e)
+			}
+		}
+	}
+
+	for i, u := range doc.units {
+		if done[i] {
+			continue
+		}
+		if u.block != nil && len(slugs) > 0 {
+			rewriteFragments(u.block, slugs)
+		}
+		unitEdits, err := s.unit(u)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, unitEdits...)
+	}
+	for _, def := range doc.refDefs {
+		if len(slugs) > 0 && body[def.destStart] == '#' {
+			if slug, ok := lookupFragment(slugs, string(body[def.destStart+1:def.destStop])); ok {
+				edits = append(edits, textSegment{start: def.destStart + 1, stop: def.destStop, text: slug})
+			}
+		}
+		if def.title == nil || strings.TrimSpace(def.title.value) == "" {
+			continue
+		}
+		out, err := s.text(def.title.value)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textSegment{start: def.title.start, stop: def.title.stop, text: def.title.render(out)})
+	}
+	for _, title := range doc.titles {
+		out, err := s.text(title.text)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textSegment{start: title.start, stop: title.stop, text: singleLine(out)})
+	}
+	if s.opts.html {
+		for _, lines := range doc.htmlBlocks {
+			out, err := s.translateHTML(lines.text(body))
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, lines.edit(body, out))
+		}
+	}
+	if s.opts.codeComments {
+		for _, cb := range doc.codeBlocks {
+			out, err := code.Translate([]byte(cb.lines.text(body)), cb.lang, s.text, code.WithStrings(s.opts.codeStrings))
+			if err != nil {
+				return nil, err
+			}
+			edits = append(edits, cb.lines.edit(body, string(out)))
+		}
+	}
+	return edits, nil
+}
+
+// unit translates one leaf block, as a whole when its placeholders survive
+// and text node by text node otherwise.
+func (s *session) unit(u translationUnit) ([]textSegment, error) {
+	var edits []textSegment
+	if u.block != nil {
+		if err := s.linkTitles(u.block); err != nil {
+			return nil, err
+		}
+		if s.opts.html {
+			if err := s.inlineHTML(u.block); err != nil {
+				return nil, err
+			}
+		}
+		if !hasText(u.block.source) {
+			return u.block.markupEdits(), nil
+		}
+		rebuilt, ok, err := s.block(u.block)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return []textSegment{{start: u.block.start, stop: u.block.stop, text: rebuilt}}, nil
+		}
+		edits = append(edits, u.block.markupEdits()...)
+	}
+	for _, seg := range u.segments {
 		if strings.TrimSpace(seg.text) == "" {
 			continue
 		}
-		total += len(chunk.Split(seg.text, maxChars))
+		out, err := s.text(seg.text)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, textSegment{start: seg.start, stop: seg.stop, text: out})
+	}
+	return edits, nil
+}
+
+// text translates a plain string, splitting it by maxChars.
+func (s *session) text(value string) (string, error) {
+	if strings.TrimSpace(value) == "" {
+		return value, nil
+	}
+	return translateChunks(s.ctx, s.tr, chunk.Split(value, s.maxChars), s.from, s.to, "text", s.progress)
+}
+
+// block translates a serialized block, in a single request when it fits
+// maxChars and in requests split at sentence ends otherwise. ok is false,
+// and the reason is reported, when a sentence is too long or the
+// placeholders did not survive translation.
+func (s *session) block(b *inlineBlock) (string, bool, error) {
+	chunks, ok := b.chunks(s.maxChars)
+	if !ok {
+		s.report(b, "a sentence is longer than the chunk size")
+		return "", false, nil
+	}
+	out, err := translateChunks(s.ctx, s.tr, chunks, s.from, s.to, b.format(), s.progress)
+	if err != nil {
+		return "", false, err
+	}
+	rebuilt, ok := b.rebuild(out)
+	if !ok {
+		s.report(b, "its inline markup did not survive translation")
 	}
-	return total
+	return rebuilt, ok, nil
 }
 
-func translateChunks(ctx context.Context, tr translate.Translator, chunks []string, from, to string, progress ProgressFunc) (string, error) {
+// report notes that b falls back to translating its text nodes one by one.
+func (s *session) report(b *inlineBlock, reason string) {
+	if s.opts.report == nil {
+		return
+	}
+	excerpt := []rune(strings.Join(strings.Fields(placeholderTagRe.ReplaceAllString(b.source, "")), " "))
+	if len(excerpt) > 40 {
+		excerpt = append(excerpt[:40], '…')
+	}
+	fmt.Fprintf(s.opts.report, "block %q: %s; translated text by text\n", string(excerpt), reason)
+}
+
+func translateChunks(ctx context.Context, tr translate.Translator, chunks []string, from, to, format string, progress ProgressFunc) (string, error) {
 	var b strings.Builder
 	for _, part := range chunks {
-		out, err := tr.Translate(ctx, part, from, to, "text")
+		out, err := tr.Translate(ctx, part, from, to, format)
 		if err != nil {
 			return "", err
 		}
@@ -83,21 +395,129 @@ func translateChunks(ctx context.Context, tr translate.Translator, chunks []stri
 	return b.String(), nil
 }
 
-func collectTextSegments(input []byte) []textSegment {
+func appendShifted(edits, more []textSegment, offset int) []textSegment {
+	for _, e := range more {
+		edits = append(edits, textSegment{start: offset + e.start, stop: offset + e.stop, text: e.text})
+	}
+	return edits
+}
+
+func applyEdits(input []byte, edits []textSegment) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	out := append([]byte(nil), input...)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		out = append(out[:e.start], append([]byte(e.text), out[e.stop:]...)...)
+	}
+	return out
+}
+
+func fitsChunk(text string, maxChars int) bool {
+	return maxChars <= 0 || len([]rune(text)) <= maxChars
+}
+
+func hasText(source string) bool {
+	return strings.TrimSpace(placeholderTagRe.ReplaceAllString(source, "")) != ""
+}
+
+func parseDocument(input []byte) (ast.Node, parser.Context) {
 	md := goldmark.New(
 		goldmark.WithExtensions(
 			extension.Strikethrough,
 			extension.Table,
 			extension.TaskList,
 			extension.Linkify,
+			extension.Footnote,
+			extension.DefinitionList,
+			extraSyntax{},
 		),
 	)
+	pc := parser.NewContext()
+	return md.Parser().Parse(text.NewReader(input), parser.WithContext(pc)), pc
+}
+
+type document struct {
+	units      []translationUnit
+	headings   []heading
+	refDefs    []referenceDef
+	htmlBlocks []blockLines
+	codeBlocks []codeBlock
+	blocks     []topBlock
+	// titles are the titles of admonitions.
+	titles []textSegment
+}
+
+// codeBlock is the content of a fenced code block in a known language.
+type codeBlock struct {
+	lines blockLines
+	lang  string
+}
 
-	reader := text.NewReader(input)
-	doc := md.Parser().Parse(reader)
+func collectDocument(input []byte) document {
+	doc, pc := parseDocument(input)
 
-	segments := make([]textSegment, 0, 64)
+	units := make([]translationUnit, 0, 64)
+	unitOf := map[ast.Node]int{}
 	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering || !isInlineContainer(n) {
+			return ast.WalkContinue, nil
+		}
+		unitOf[n] = len(units)
+		u := translationUnit{segments: collectTextSegments(n, input, alertMarkerEnd(input, n))}
+		if block, err := serializeBlock(input, n); err == nil {
+			u.block = block
+		}
+		units = append(units, u)
+		return ast.WalkSkipChildren, nil
+	})
+
+	return document{
+		units:      units,
+		headings:   collectHeadings(input, doc, unitOf),
+		refDefs:    referenceDefs(input, doc, pc),
+		htmlBlocks: htmlBlocks(doc),
+		codeBlocks: fencedCodeBlocks(input, doc),
+		blocks:     topLevelBlocks(input, doc),
+		titles:     admonitionTitles(input, doc),
+	}
+}
+
+// fencedCodeBlocks returns fenced code blocks with a known language,
+// including those nested in list items and blockquotes.
+func fencedCodeBlocks(input []byte, doc ast.Node) []codeBlock {
+	var blocks []codeBlock
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		fenced, ok := n.(*ast.FencedCodeBlock)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		lang := code.LanguageFromInfo(string(fenced.Language(input)))
+		if lang == "" {
+			return ast.WalkSkipChildren, nil
+		}
+		if lines, ok := linesOf(fenced.Lines(), nil); ok {
+			blocks = append(blocks, codeBlock{lines: lines, lang: lang})
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	return blocks
+}
+
+func isInlineContainer(n ast.Node) bool {
+	if n.Type() != ast.TypeBlock || !n.HasChildren() {
+		return false
+	}
+	return n.FirstChild().Type() == ast.TypeInline
+}
+
+// collectTextSegments returns the translatable text nodes under root, ignoring
+// those that end at or before skipUntil.
+func collectTextSegments(root ast.Node, input []byte, skipUntil int) []textSegment {
+	segments := make([]textSegment, 0, 8)
+	ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
 		if !entering {
 			return ast.WalkContinue, nil
 		}
@@ -114,7 +534,7 @@ func collectTextSegments(input []byte) []textSegment {
 		}
 
 		seg := textNode.Segment
-		if seg.Start >= seg.Stop {
+		if seg.Start >= seg.Stop || seg.Stop <= skipUntil {
 			return ast.WalkContinue, nil
 		}
 		segments = append(segments, textSegment{
@@ -124,7 +544,6 @@ func collectTextSegments(input []byte) []textSegment {
 		})
 		return ast.WalkContinue, nil
 	})
-
 	return segments
 }
 
@@ -136,7 +555,9 @@ func shouldTranslateNode(n ast.Node) bool {
 			*ast.CodeSpan,
 			*ast.HTMLBlock,
 			*ast.RawHTML,
-			*ast.AutoLink:
+			*ast.AutoLink,
+			*mathInline,
+			*east.FootnoteLink:
 			return false
 		}
 	}
@@ -1,6 +1,7 @@
 package markdown
 
 import (
+	"bytes"
 	"context"
 	"strings"
 	"testing"
@@ -58,3 +59,133 @@ func TestTranslateMarkdownProgress(t *testing.T) {
 		t.Fatalf("expected progress callbacks, got none")
 	}
 }
+
+type mapTranslator struct {
+	m      map[string]string
+	inputs []string
+}
+
+func (tr *mapTranslator) Translate(ctx context.Context, text, from, to, format string) (string, error) {
+	_ = ctx
+	_ = from
+	_ = to
+	_ = format
+	tr.inputs = append(tr.inputs, text)
+	if out, ok := tr.m[text]; ok {
+		return out, nil
+	}
+	return text, nil
+}
+
+func TestTranslateMarkdownBlockLevel(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"Click <b1>Save</b1> to continue with <code2/>.": "<code2/> で続けるには<b1>保存</b1>をクリックします。",
+	}}
+	input := "Click **Save** to continue with `go run`.\n"
+	got, err := Translate(context.Background(), tr, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if len(tr.inputs) != 1 {
+		t.Fatalf("expected one request per paragraph, got %q", tr.inputs)
+	}
+	want := "`go run` で続けるには**保存**をクリックします。\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestTranslateMarkdownBlockLevelLinksAndBreaks(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"See <link1>the docs</link1> and <i2>more</i2><br3/>next line": "<i2>詳細</i2>と<link1>ドキュメント</link1>を参照<br3/>次の行",
+	}}
+	input := "> See [the docs](https://example.com \"Docs\") and *more*  \n> next line\n"
+	got, err := Translate(context.Background(), tr, []byte(input), "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "> *詳細*と[ドキュメント](https://example.com \"Docs\")を参照  \n> 次の行\n"
+	if string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestTranslateMarkdownPlaceholderMismatchFallsBack(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"Click <b1>Save</b1> now": "保存をクリック",
+		"Click ":                  "クリック ",
+		"Save":                    "保存",
+		" now":                    " 今",
+	}}
+	var report bytes.Buffer
+	got, err := Translate(context.Background(), tr, []byte("Click **Save** now\n"), "en", "ja", WithReport(&report))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if string(got) != "クリック **保存** 今\n" {
+		t.Fatalf("got %q", got)
+	}
+	if want := "block \"Click Save now\": its inline markup did not survive translation; translated text by text\n"; report.String() != want {
+		t.Fatalf("report %q", report.String())
+	}
+}
+
+func TestTranslateMarkdownSplitsLongBlocks(t *testing.T) {
+	tr := &mapTranslator{m: map[string]string{
+		"First <b1>bold</b1> sentence. ":  "最初の<b1>太字</b1>の文。",
+		"Second <code2/> one. Third one.": "二番目の<code2/>の文。三番目の文。",
+	}}
+	input := "First **bold** sentence. Second `code` one. Third one.\n"
+	var report bytes.Buffer
+	got, err := TranslateWithProgress(context.Background(), tr, []byte(input), "en", "ja", 40, nil, WithReport(&report))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if len(tr.inputs) != 2 {
+		t.Fatalf("inputs %q", tr.inputs)
+	}
+	if want := "最初の**太字**の文。二番目の`code`の文。三番目の文。\n"; string(got) != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if report.Len() != 0 {
+		t.Fatalf("report %q", report.String())
+	}
+
+	// A sentence that does not fit is translated text node by text node, and
+	// reported.
+	tr = &mapTranslator{}
+	input = "A **very long sentence** that does not fit.\n"
+	if _, err := TranslateWithProgress(context.Background(), tr, []byte(input), "en", "ja", 20, nil, WithReport(&report)); err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	if !strings.Contains(report.String(), "a sentence is longer than the chunk size") {
+		t.Fatalf("report %q", report.String())
+	}
+}
+
+func TestCodeCommentsTranslated(t *testing.T) {
+	input := "Run:\n\n```sh\n# Install the package\nnpm install x\n```\n\n```\n# untouched\n```\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithCodeComments(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "RUN:\n\n```sh\n# INSTALL THE PACKAGE\nnpm install x\n```\n\n```\n# untouched\n```\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+	if n := CountChunks([]byte(input), 0, WithCodeComments(true)); n != 2 {
+		t.Fatalf("CountChunks = %d, want 2", n)
+	}
+}
+
+func TestNestedCodeCommentsTranslated(t *testing.T) {
+	input := "1. Install:\n\n   ```sh\n   # Install the package\n\n   npm install x\n   ```\n\n> ```py\n> x = 1  # set x\n> ```\n"
+	got, err := Translate(context.Background(), upperTranslator{}, []byte(input), "en", "ja", WithCodeComments(true))
+	if err != nil {
+		t.Fatalf("Translate error: %v", err)
+	}
+	want := "1. INSTALL:\n\n   ```sh\n   # INSTALL THE PACKAGE\n\n   npm install x\n   ```\n\n> ```py\n> x = 1  # SET X\n> ```\n"
+	if string(got) != want {
+		t.Fatalf("got %q\nwant %q", got, want)
+	}
+}
@@ -0,0 +1,274 @@
+package pdf
+
+import (
+	"fmt"
+
+	"github.com/unidoc/unipdf/v4/contentstream"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// Redaction modes for WithRedaction.
+const (
+	// RedactCover paints over the original text in the colour of the
+	// background behind it.
+	RedactCover = "cover"
+	// RedactRemove deletes the original text-showing operators from the
+	// page content, so that only the translation remains.
+	RedactRemove = "remove"
+)
+
+// WithRedaction selects how the original text is hidden. The default is
+// RedactCover.
+func WithRedaction(mode string) Option {
+	return func(o *options) {
+		o.redaction = mode
+	}
+}
+
+// filledRect is a rectangle painted with a solid colour, in page space.
+type filledRect struct {
+	box model.PdfRectangle
+	rgb [3]float64
+}
+
+// pageGraphics returns the solid filled rectangles painted by the content
+// stream of page, in painting order, and its ruling lines: the horizontal
+// and vertical strokes and thin filled rectangles that draw tables. Rules
+// are returned as boxes at most ruleWidth thick. Other paths, images and
+// form XObjects are not considered.
+func pageGraphics(page *model.PdfPage) ([]filledRect, []model.PdfRectangle, error) {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return nil, nil, err
+	}
+	ops, err := contentstream.NewContentStreamParser(contents).Parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var fills, pending []filledRect
+	var rules, segments []model.PdfRectangle
+	var x, y, startX, startY float64
+	lineTo := func(gs contentstream.GraphicsState, toX, toY float64) {
+		x0, y0 := gs.Transform(x, y)
+		x1, y1 := gs.Transform(toX, toY)
+		segments = append(segments, model.PdfRectangle{
+			Llx: minFloat(x0, x1), Lly: minFloat(y0, y1),
+			Urx: maxFloat(x0, x1), Ury: maxFloat(y0, y1),
+		})
+		x, y = toX, toY
+	}
+	processor := contentstream.NewContentStreamProcessor(*ops)
+	processor.SetRelaxedMode(true)
+	processor.AddHandler(contentstream.HandlerConditionEnumAllOperands, "", func(op *contentstream.ContentStreamOperation, gs contentstream.GraphicsState, _ *model.PdfPageResources) error {
+		switch op.Operand {
+		case "m", "l":
+			v, err := core.GetNumbersAsFloat(op.Params)
+			if err != nil || len(v) != 2 {
+				return nil
+			}
+			if op.Operand == "m" {
+				x, y = v[0], v[1]
+				startX, startY = x, y
+			} else {
+				lineTo(gs, v[0], v[1])
+			}
+		case "h":
+			lineTo(gs, startX, startY)
+		case "re":
+			v, err := core.GetNumbersAsFloat(op.Params)
+			if err != nil || len(v) != 4 {
+				return nil
+			}
+			x0, y0 := gs.Transform(v[0], v[1])
+			x1, y1 := gs.Transform(v[0]+v[2], v[1]+v[3])
+			pending = append(pending, filledRect{box: model.PdfRectangle{
+				Llx: minFloat(x0, x1), Lly: minFloat(y0, y1),
+				Urx: maxFloat(x0, x1), Ury: maxFloat(y0, y1),
+			}})
+			x, y = v[0], v[1]
+			startX, startY = x, y
+			for _, p := range [][2]float64{{v[0] + v[2], v[1]}, {v[0] + v[2], v[1] + v[3]}, {v[0], v[1] + v[3]}, {v[0], v[1]}} {
+				lineTo(gs, p[0], p[1])
+			}
+		case "f", "F", "f*", "B", "B*", "b", "b*":
+			if rgb, ok := fillRGB(gs); ok {
+				for _, r := range pending {
+					r.rgb = rgb
+					fills = append(fills, r)
+				}
+			}
+			for _, r := range pending {
+				if isRule(r.box) {
+					rules = append(rules, r.box)
+				}
+			}
+			if op.Operand[0] == 'B' || op.Operand[0] == 'b' {
+				rules = appendRules(rules, segments)
+			}
+			pending, segments = nil, nil
+		case "S", "s":
+			rules = appendRules(rules, segments)
+			pending, segments = nil, nil
+		case "n":
+			pending, segments = nil, nil
+		}
+		return nil
+	})
+	if err := processor.Process(page.Resources); err != nil {
+		return nil, nil, err
+	}
+	return fills, rules, nil
+}
+
+// ruleWidth is the thickest box that counts as a ruling line, and
+// ruleLength the shortest.
+const (
+	ruleWidth  = 2.0
+	ruleLength = 5.0
+)
+
+// isRule reports whether box is a horizontal or vertical line.
+func isRule(box model.PdfRectangle) bool {
+	w, h := box.Urx-box.Llx, box.Ury-box.Lly
+	return (h <= ruleWidth && w >= ruleLength) || (w <= ruleWidth && h >= ruleLength)
+}
+
+func appendRules(rules, segments []model.PdfRectangle) []model.PdfRectangle {
+	for _, s := range segments {
+		if isRule(s) {
+			rules = append(rules, s)
+		}
+	}
+	return rules
+}
+
+func fillRGB(gs contentstream.GraphicsState) ([3]float64, bool) {
+	if gs.ColorspaceNonStroking == nil || gs.ColorNonStroking == nil {
+		return [3]float64{}, false
+	}
+	color, err := gs.ColorspaceNonStroking.ColorToRGB(gs.ColorNonStroking)
+	if err != nil {
+		return [3]float64{}, false
+	}
+	rgb, ok := color.(*model.PdfColorDeviceRGB)
+	if !ok {
+		return [3]float64{}, false
+	}
+	return [3]float64{rgb.R(), rgb.G(), rgb.B()}, true
+}
+
+// backgroundAt returns the colour of the topmost fill under the centre of
+// box, or white.
+func backgroundAt(fills []filledRect, box model.PdfRectangle) creator.Color {
+	x, y := (box.Llx+box.Urx)/2, (box.Lly+box.Ury)/2
+	for i := len(fills) - 1; i >= 0; i-- {
+		f := fills[i].box
+		if x >= f.Llx && x <= f.Urx && y >= f.Lly && y <= f.Ury {
+			rgb := fills[i].rgb
+			return creator.ColorRGBFromArithmetic(rgb[0], rgb[1], rgb[2])
+		}
+	}
+	return creator.ColorWhite
+}
+
+// removeText deletes the text-showing operators from the content stream of
+// page and from the form XObjects it draws. Forms may be shared with other
+// pages, or with the original page kept next to the translation, so the
+// page draws stripped copies of them instead.
+func removeText(page *model.PdfPage) error {
+	contents, err := page.GetAllContentStreams()
+	if err != nil {
+		return err
+	}
+	kept, _, err := stripText(contents, page.Resources, 0)
+	if err != nil {
+		return err
+	}
+	return page.SetContentStreams([]string{kept}, core.NewFlateEncoder())
+}
+
+// maxFormDepth limits how deeply forms drawn by forms are followed.
+const maxFormDepth = 8
+
+// stripText returns content without its text-showing operators, and
+// whether it changed. Forms named in resources are replaced by copies
+// without text.
+func stripText(content string, resources *model.PdfPageResources, depth int) (string, bool, error) {
+	ops, err := contentstream.NewContentStreamParser(content).Parse()
+	if err != nil {
+		return "", false, fmt.Errorf("parse content stream: %w", err)
+	}
+	changed := false
+	copies := map[core.PdfObjectName]core.PdfObjectName{}
+	kept := make(contentstream.ContentStreamOperations, 0, len(*ops))
+	for _, op := range *ops {
+		switch op.Operand {
+		case "Tj", "TJ", "'", "\"":
+			changed = true
+			continue
+		case "Do":
+			name, ok := core.GetName(firstParam(op))
+			if !ok || resources == nil || depth >= maxFormDepth {
+				break
+			}
+			stripped, seen := copies[*name]
+			if !seen {
+				stripped = stripForm(resources, *name, depth)
+				copies[*name] = stripped
+			}
+			if stripped != *name {
+				op = &contentstream.ContentStreamOperation{Operand: "Do", Params: []core.PdfObject{core.MakeName(string(stripped))}}
+				changed = true
+			}
+		}
+		kept = append(kept, op)
+	}
+	return kept.String(), changed, nil
+}
+
+// stripForm adds a copy of the form XObject name without its text to
+// resources and returns the name of the copy. Other XObjects, forms without
+// text and forms that cannot be read keep their name.
+func stripForm(resources *model.PdfPageResources, name core.PdfObjectName, depth int) core.PdfObjectName {
+	if _, kind := resources.GetXObjectByName(name); kind != model.XObjectTypeForm {
+		return name
+	}
+	form, err := resources.GetXObjectFormByName(name)
+	if err != nil || form == nil {
+		return name
+	}
+	content, err := form.GetContentStream()
+	if err != nil {
+		return name
+	}
+	inner := form.Resources
+	if inner == nil {
+		// The form uses the resources of the page.
+		inner = resources
+	}
+	stripped, changed, err := stripText(string(content), inner, depth+1)
+	if err != nil || !changed {
+		return name
+	}
+	dup := model.NewXObjectForm()
+	dup.FormType, dup.BBox, dup.Matrix, dup.Resources = form.FormType, form.BBox, form.Matrix, form.Resources
+	dup.Group, dup.OC, dup.Name = form.Group, form.OC, form.Name
+	if err := dup.SetContentStream([]byte(stripped), core.NewFlateEncoder()); err != nil {
+		return name
+	}
+	copyName := resources.GenerateXObjectName()
+	if err := resources.SetXObjectFormByName(copyName, dup); err != nil {
+		return name
+	}
+	return copyName
+}
+
+func firstParam(op *contentstream.ContentStreamOperation) core.PdfObject {
+	if len(op.Params) == 0 {
+		return nil
+	}
+	return op.Params[0]
+}
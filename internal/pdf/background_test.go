@@ -0,0 +1,116 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func testPage(t *testing.T, content string) *model.PdfPage {
+	t.Helper()
+	page := model.NewPdfPage()
+	page.MediaBox = &model.PdfRectangle{Urx: 612, Ury: 792}
+	if err := page.SetContentStreams([]string{content}, core.NewRawEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func TestPageFillsAndBackground(t *testing.T) {
+	page := testPage(t, "1 1 1 rg 0 0 612 792 re f\n"+
+		"q 2 0 0 2 0 0 cm 0 0 0.5 rg 36 350 100 20 re f Q\n"+
+		"0 0 1 RG 0 0 10 10 re S\n"+
+		"BT /F1 12 Tf 80 710 Td (Hi) Tj ET\n")
+	fills, _, err := pageGraphics(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 2 {
+		t.Fatalf("fills=%+v", fills)
+	}
+	if box := fills[1].box; box.Llx != 72 || box.Lly != 700 || box.Urx != 272 || box.Ury != 740 {
+		t.Fatalf("box=%+v", box)
+	}
+
+	inside := backgroundAt(fills, model.PdfRectangle{Llx: 80, Lly: 710, Urx: 120, Ury: 722})
+	if inside != creator.ColorRGBFromArithmetic(0, 0, 0.5) {
+		t.Fatalf("inside=%v", inside)
+	}
+	outside := backgroundAt(fills, model.PdfRectangle{Llx: 300, Lly: 100, Urx: 340, Ury: 112})
+	if outside != creator.ColorRGBFromArithmetic(1, 1, 1) {
+		t.Fatalf("outside=%v", outside)
+	}
+	if backgroundAt(nil, model.PdfRectangle{}) != creator.ColorWhite {
+		t.Fatal("empty page is not white")
+	}
+}
+
+func TestRemoveText(t *testing.T) {
+	page := testPage(t, "0 0 1 rg 0 0 100 100 re f\nBT /F1 12 Tf 10 10 Td (Hi) Tj [(A) 10 (B)] TJ (C) ' ET\n")
+	if err := removeText(page); err != nil {
+		t.Fatal(err)
+	}
+	out, err := page.GetAllContentStreams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range []string{"Tj", "TJ", "'", "(Hi)"} {
+		if strings.Contains(out, op) {
+			t.Fatalf("%q left in %q", op, out)
+		}
+	}
+	if !strings.Contains(out, "re") || !strings.Contains(out, "Tf") {
+		t.Fatalf("drawing lost: %q", out)
+	}
+}
+
+func TestRemoveTextInForms(t *testing.T) {
+	form := model.NewXObjectForm()
+	form.BBox = core.MakeArrayFromFloats([]float64{0, 0, 100, 100})
+	if err := form.SetContentStream([]byte("0 0 m 10 10 l S BT /F1 12 Tf (Logo) Tj ET"), core.NewRawEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	shape := model.NewXObjectForm()
+	shape.BBox = form.BBox
+	if err := shape.SetContentStream([]byte("0 0 10 10 re f"), core.NewRawEncoder()); err != nil {
+		t.Fatal(err)
+	}
+	page := testPage(t, "/Fm1 Do /Fm2 Do BT (Hi) Tj ET")
+	page.Resources = model.NewPdfPageResources()
+	if err := page.Resources.SetXObjectFormByName("Fm1", form); err != nil {
+		t.Fatal(err)
+	}
+	if err := page.Resources.SetXObjectFormByName("Fm2", shape); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removeText(page); err != nil {
+		t.Fatal(err)
+	}
+	out, err := page.GetAllContentStreams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out, "/Fm1 Do") || !strings.Contains(out, "/Fm2 Do") || strings.Contains(out, "Tj") {
+		t.Fatalf("page content %q", out)
+	}
+	name := strings.Fields(out)[0][1:]
+	dup, err := page.Resources.GetXObjectFormByName(core.PdfObjectName(name))
+	if err != nil || dup == nil {
+		t.Fatalf("copy %q: %v", name, err)
+	}
+	content, err := dup.GetContentStream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "Logo") || !strings.Contains(string(content), "l") {
+		t.Fatalf("copied form %q", content)
+	}
+	// The original form, which other pages may draw, keeps its text.
+	if original, _ := form.GetContentStream(); !strings.Contains(string(original), "(Logo) Tj") {
+		t.Fatalf("original form %q", original)
+	}
+}
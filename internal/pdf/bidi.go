@@ -0,0 +1,135 @@
+package pdf
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/unidoc/garabic"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// mirrored maps brackets to their mirror images, which right-to-left runs
+// show reversed.
+var mirrored = map[rune]rune{
+	'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{',
+	'<': '>', '>': '<', '«': '»', '»': '«',
+}
+
+// isRTL reports whether text is written right to left: its first strongly
+// directional character is Hebrew, Arabic or another right-to-left script.
+func isRTL(text string) bool {
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.R, bidi.AL:
+			return true
+		case bidi.L:
+			return false
+		}
+	}
+	return false
+}
+
+// hasRTL reports whether text contains any right-to-left character.
+func hasRTL(text string) bool {
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		if c := props.Class(); c == bidi.R || c == bidi.AL {
+			return true
+		}
+	}
+	return false
+}
+
+// visualOrder returns line as it is drawn from left to right: the runs of
+// the Unicode bidirectional algorithm in display order, with right-to-left
+// runs reversed and Arabic letters in their joined forms. rtl is the
+// direction of the paragraph the line belongs to. PDF text is drawn in the
+// order of its characters, so overlays need this order.
+func visualOrder(line string, rtl bool) string {
+	dir := bidi.LeftToRight
+	if rtl {
+		dir = bidi.RightToLeft
+	}
+	var p bidi.Paragraph
+	if _, err := p.SetString(line, bidi.DefaultDirection(dir)); err != nil {
+		return line
+	}
+	order, err := p.Order()
+	if err != nil {
+		return line
+	}
+	runs := make([]string, order.NumRuns())
+	for i := range runs {
+		run := order.Run(i)
+		runs[i] = run.String()
+		if run.Direction() == bidi.RightToLeft {
+			runs[i] = reverseRun(runs[i])
+		}
+	}
+	if dir == bidi.RightToLeft {
+		for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+			runs[i], runs[j] = runs[j], runs[i]
+		}
+	}
+	return strings.Join(runs, "")
+}
+
+// reverseRun reverses a right-to-left run, shaping Arabic words and
+// mirroring brackets.
+func reverseRun(run string) string {
+	var b strings.Builder
+	words := splitSpaces(run)
+	for i := len(words) - 1; i >= 0; i-- {
+		word := words[i]
+		if strings.IndexFunc(word, garabic.IsArabicLetter) >= 0 {
+			// Shape returns the joined forms already reversed.
+			b.WriteString(garabic.Shape(word))
+			continue
+		}
+		runes := []rune(word)
+		for j := len(runes) - 1; j >= 0; j-- {
+			r := runes[j]
+			if m, ok := mirrored[r]; ok {
+				r = m
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// splitSpaces splits s into words and the runs of spaces between them.
+func splitSpaces(s string) []string {
+	var parts []string
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			parts = append(parts, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// visualLines puts the lines of a translation that hold right-to-left text
+// in visual order. The lines of a right-to-left translation are also
+// indented so that they end at the right edge of a box width wide, measured
+// as drawn, with Arabic letters in their joined forms.
+func visualLines(fit *fitted, rtl bool, width float64, measure func(string) float64) {
+	if rtl {
+		fit.indent = make([]float64, len(fit.lines))
+	}
+	for i, line := range fit.lines {
+		if hasRTL(line) {
+			fit.lines[i] = visualOrder(line, rtl)
+		}
+		if rtl {
+			fit.indent[i] = max(width-measure(fit.lines[i])*fit.size, 0)
+		}
+	}
+}
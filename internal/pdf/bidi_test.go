@@ -0,0 +1,82 @@
+package pdf
+
+import "testing"
+
+func TestIsRTL(t *testing.T) {
+	cases := map[string]bool{
+		"שלום עולם":    true,
+		"مرحبا":        true,
+		"123 שלום":     true,
+		"hello שלום":   false,
+		"":             false,
+		"(2024) עדכון": true,
+	}
+	for text, want := range cases {
+		if got := isRTL(text); got != want {
+			t.Errorf("isRTL(%q)=%v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	cases := []struct {
+		in   string
+		rtl  bool
+		want string
+	}{
+		{"abc", false, "abc"},
+		// Hebrew letters are reversed; numbers and Latin words keep their
+		// order but move as a whole.
+		{"שלום עולם", true, "םלוע םולש"},
+		{"גרסה 2 (בטא)", true, "(אטב) 2 הסרג"},
+		{"hello עולם world", false, "hello םלוע world"},
+		// A line of a right-to-left paragraph that starts with a Latin word.
+		{"PDF קובץ", true, "ץבוק PDF"},
+		// Arabic letters take their joined forms.
+		{"مرحبا", true, "ﺎﺒﺣﺮﻣ"},
+	}
+	for _, c := range cases {
+		if got := visualOrder(c.in, c.rtl); got != c.want {
+			t.Errorf("visualOrder(%q)=%q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestVisualLines(t *testing.T) {
+	runes := func(s string) float64 { return 0.5 * float64(len([]rune(s))) }
+	fit := fitted{size: 10, lines: []string{"שלום", "עולם טוב"}}
+	visualLines(&fit, true, 100, runes)
+	if fit.indent[0] != 80 || fit.indent[1] != 60 {
+		t.Fatalf("indent=%v", fit.indent)
+	}
+	if fit.lines[0] != "םולש" {
+		t.Fatalf("lines=%q", fit.lines)
+	}
+
+	// Fonts give the joined forms of Arabic letters their own widths, so
+	// the shaped line is measured.
+	joined := func(s string) float64 {
+		w := 0.0
+		for _, r := range s {
+			if r >= 0xfb50 {
+				w += 0.5
+			} else {
+				w += 1
+			}
+		}
+		return w
+	}
+	fit = fitted{size: 10, lines: []string{"مرحبا"}}
+	visualLines(&fit, true, 100, joined)
+	if fit.indent[0] != 75 {
+		t.Fatalf("shaped indent=%v lines=%q", fit.indent, fit.lines)
+	}
+
+	// Right-to-left words in a left-to-right translation are reordered on
+	// every line, not only when the block starts with them.
+	fit = fitted{size: 10, lines: []string{"see", "the עולם page"}}
+	visualLines(&fit, false, 100, runes)
+	if fit.indent != nil || fit.lines[1] != "the םלוע page" {
+		t.Fatalf("lines=%q indent=%v", fit.lines, fit.indent)
+	}
+}
@@ -0,0 +1,355 @@
+package pdf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fuba/translate/internal/chunk"
+	"github.com/fuba/translate/internal/lang"
+	"github.com/fuba/translate/internal/translate"
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// Document is an opened PDF whose selected pages have been extracted and
+// laid out once, so that counting, dumping and translating share one parse.
+type Document struct {
+	file     *os.File
+	reader   *model.PdfReader
+	password string
+	count    int
+	pages    []extractedPage
+	meta     docMetadata
+	opts     options
+}
+
+// extractedPage is the layout analysis of one page.
+type extractedPage struct {
+	number   int
+	page     *model.PdfPage
+	mediaBox model.PdfRectangle
+	rotate   int64
+	// width and height are the size of the page as displayed. The boxes of
+	// blocks and fills are in this space, with the origin at the bottom
+	// left corner.
+	width, height float64
+	blocks        []textBlock
+	// fills are the solid rectangles painted on the page, used to match
+	// cover boxes to the background.
+	fills []filledRect
+	// annotations are the page's annotations with text to translate.
+	annotations []*model.PdfAnnotation
+}
+
+// Open reads and decrypts inPath and extracts the text of its selected
+// pages. The Document must be closed after use.
+func Open(inPath, unidocKey string, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	switch o.layout {
+	case LayoutOverlay, LayoutSideBySide, LayoutInterleavePages:
+	default:
+		return nil, fmt.Errorf("unknown pdf layout: %s", o.layout)
+	}
+	switch o.redaction {
+	case RedactCover, RedactRemove:
+	default:
+		return nil, fmt.Errorf("unknown pdf redaction mode: %s", o.redaction)
+	}
+
+	if strings.TrimSpace(unidocKey) == "" {
+		return nil, errors.New("unidoc key is required for PDF input")
+	}
+	if err := setLicense(unidocKey); err != nil {
+		return nil, fmt.Errorf("set unidoc license: %w", err)
+	}
+
+	f, err := os.Open(inPath)
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{file: f, opts: o}
+	if err := doc.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+func (d *Document) load() error {
+	reader, err := model.NewPdfReader(d.file)
+	if err != nil {
+		return err
+	}
+	d.reader = reader
+
+	if d.password, err = decrypt(reader, d.opts); err != nil {
+		return err
+	}
+
+	if d.count, err = reader.GetNumPages(); err != nil {
+		return err
+	}
+	numbers, err := selectedPages(d.opts.pages, d.count)
+	if err != nil {
+		return err
+	}
+
+	for _, pageNum := range numbers {
+		page, err := reader.GetPage(pageNum)
+		if err != nil {
+			return err
+		}
+		p, err := extractPage(page)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		p.number = pageNum
+		d.pages = append(d.pages, p)
+	}
+	d.meta = readMetadata(reader)
+	return nil
+}
+
+func extractPage(page *model.PdfPage) (extractedPage, error) {
+	ex, err := extractor.New(page)
+	if err != nil {
+		return extractedPage{}, err
+	}
+	pageText, _, _, err := ex.ExtractPageText()
+	if err != nil {
+		return extractedPage{}, err
+	}
+	mediaBox, err := page.GetMediaBox()
+	if err != nil {
+		return extractedPage{}, err
+	}
+	if page.MediaBox == nil {
+		page.MediaBox = mediaBox
+	}
+	rotate, _ := page.GetRotate()
+	// The background is best effort: pages whose drawing cannot be
+	// followed get white cover boxes.
+	fills, rules, _ := pageGraphics(page)
+	for i := range fills {
+		fills[i].box = toDisplay(fills[i].box, *mediaBox, int(rotate))
+	}
+	for i := range rules {
+		rules[i] = toDisplay(rules[i], *mediaBox, int(rotate))
+	}
+	width, height := pageSize(*mediaBox, int(rotate))
+	return extractedPage{
+		fills:    fills,
+		page:     page,
+		mediaBox: *mediaBox,
+		rotate:   rotate,
+		width:    width,
+		height:   height,
+		blocks:   pageBlocks(pageText.Marks().Elements(), *mediaBox, int(rotate), rules),
+
+		annotations: pageAnnotations(page),
+	}, nil
+}
+
+// Close closes the underlying file.
+func (d *Document) Close() error {
+	return d.file.Close()
+}
+
+// CountChunks returns the number of translation requests Translate makes
+// when each block is split by maxChars. Annotations, metadata and form
+// strings are translated once per distinct text, as Translate does.
+func (d *Document) CountChunks(maxChars int) int {
+	total := 0
+	count := func(text string) {
+		if strings.TrimSpace(text) != "" {
+			total += len(chunk.Split(text, maxChars))
+		}
+	}
+	seen := map[string]bool{}
+	countOnce := func(text string) {
+		if !seen[text] {
+			seen[text] = true
+			count(text)
+		}
+	}
+	for _, p := range d.pages {
+		for _, block := range p.blocks {
+			count(block.Text)
+		}
+		if d.opts.layout == LayoutOverlay {
+			for _, a := range p.annotations {
+				s, _ := core.GetString(a.Contents)
+				countOnce(s.Decoded())
+			}
+		}
+	}
+	for _, text := range d.meta.texts() {
+		countOnce(text)
+	}
+	if form := d.form(); form != nil {
+		for _, text := range formTexts(form) {
+			countOnce(text)
+		}
+	}
+	return total
+}
+
+// form returns the interactive form of the document when the output keeps
+// it: every page is written once and widgets stay on their pages, which
+// LayoutSideBySide does not do and LayoutInterleavePages, which writes the
+// original pages next to their translations with the same widgets, does
+// not either.
+func (d *Document) form() *model.PdfAcroForm {
+	if d.reader == nil || d.opts.selectedOnly || d.opts.layout == LayoutSideBySide || d.opts.layout == LayoutInterleavePages {
+		return nil
+	}
+	return d.reader.AcroForm
+}
+
+// Text returns the text blocks of the selected pages in reading order, with
+// their positions.
+func (d *Document) Text() string {
+	numbers := make([]int, 0, len(d.pages))
+	texts := make([]string, 0, len(d.pages))
+	for _, p := range d.pages {
+		numbers = append(numbers, p.number)
+		texts = append(texts, formatBlocks(p.blocks))
+	}
+	return joinPageText(numbers, texts)
+}
+
+// JSON returns the extracted pages as JSON.
+func (d *Document) JSON() ([]byte, error) {
+	return dumpJSON(d.pages)
+}
+
+// outputPages maps input page indexes to the output page outline items
+// point at: the translated copy of a selected page, which follows the
+// original with LayoutInterleavePages. Unselected pages are left out of the
+// map with WithSelectedPagesOnly.
+func (d *Document) outputPages() map[int64]int64 {
+	pages := map[int64]int64{}
+	output := int64(0)
+	next := 0
+	for pageNum := 1; pageNum <= d.count; pageNum++ {
+		if next < len(d.pages) && d.pages[next].number == pageNum {
+			next++
+			if d.opts.layout == LayoutInterleavePages {
+				output++
+			}
+		} else if d.opts.selectedOnly {
+			continue
+		}
+		pages[int64(pageNum-1)] = output
+		output++
+	}
+	return pages
+}
+
+// Translate translates the selected pages and writes the document to
+// outPath. Pages that are not selected are copied unchanged, or left out
+// with WithSelectedPagesOnly. The outline, the document title and subject,
+// form field tooltips and choice labels and, with LayoutOverlay, the text
+// of annotations on translated pages are translated too, and the document
+// language is set to to.
+func (d *Document) Translate(ctx context.Context, tr translate.Translator, outPath, from, to string, maxChars int, progress func(string), fontPath string) error {
+	o := d.opts
+	c := creator.New()
+	var encrypt func(*model.PdfWriter) error
+	if o.keepEncryption {
+		var err error
+		if encrypt, err = keepEncryption(d.reader, d.password); err != nil {
+			return err
+		}
+	}
+	fonts, err := loadFontSet(fontPath, o)
+	if err != nil {
+		return err
+	}
+	translated := map[string]string{}
+	translateText := func(text string) (string, error) {
+		if out, ok := translated[text]; ok {
+			return out, nil
+		}
+		out, err := translateChunked(ctx, tr, text, from, to, maxChars, progress)
+		if err != nil {
+			return "", err
+		}
+		translated[text] = out
+		return out, nil
+	}
+
+	next := 0
+	for pageNum := 1; pageNum <= d.count; pageNum++ {
+		if next >= len(d.pages) || d.pages[next].number != pageNum {
+			if o.selectedOnly {
+				continue
+			}
+			page, err := d.reader.GetPage(pageNum)
+			if err != nil {
+				return err
+			}
+			if err := c.AddPage(page); err != nil {
+				return fmt.Errorf("page %d: %w", pageNum, err)
+			}
+			continue
+		}
+		p := d.pages[next]
+		next++
+
+		if progress != nil {
+			progress(fmt.Sprintf("[page %d] translating", pageNum))
+		}
+		if o.layout == LayoutOverlay {
+			// The other layouts keep the original page, which shares the
+			// annotation objects.
+			if err := translateAnnotations(p.annotations, translateText); err != nil {
+				return fmt.Errorf("page %d: %w", pageNum, err)
+			}
+		}
+		warnings, err := addTranslatedPage(ctx, tr, c, p, o, from, to, maxChars, progress, fonts)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", pageNum, err)
+		}
+		if o.report != nil {
+			for _, w := range warnings {
+				fmt.Fprintf(o.report, "page %d: %s\n", pageNum, w)
+			}
+		}
+	}
+
+	meta := d.meta
+	if err := meta.translate(translateText, d.outputPages()); err != nil {
+		return err
+	}
+	if form := d.form(); form != nil {
+		if err := translateForm(form, translateText); err != nil {
+			return err
+		}
+		if err := c.SetForms(form); err != nil {
+			return err
+		}
+	}
+	if meta.outline != nil {
+		c.SetOutlineTree(&outlineTree(meta.outline).PdfOutlineTreeNode)
+	}
+	if tag, ok := lang.Tag(to); ok {
+		c.SetLanguage(tag)
+	}
+	c.SetPdfWriterAccessFunc(func(w *model.PdfWriter) error {
+		if err := meta.write(w); err != nil {
+			return err
+		}
+		if encrypt != nil {
+			return encrypt(w)
+		}
+		return nil
+	})
+
+	return c.WriteToFile(outPath)
+}
@@ -0,0 +1,24 @@
+package pdf
+
+import "testing"
+
+func TestDocumentCountAndText(t *testing.T) {
+	doc := &Document{
+		count: 3,
+		pages: []extractedPage{
+			{number: 2, blocks: groupParagraphs([]textLine{
+				line("First block of text.", 72, 700, 300),
+				line("Second block.", 72, 600, 300),
+			})},
+		},
+	}
+	if got := doc.CountChunks(0); got != 2 {
+		t.Fatalf("chunks=%d", got)
+	}
+	want := "=== Page 2 ===\n" +
+		"--- block 1 (x=72 y=700 w=228 h=10) ---\nFirst block of text.\n\n" +
+		"--- block 2 (x=72 y=600 w=228 h=10) ---\nSecond block.\n\n\n"
+	if got := doc.Text(); got != want {
+		t.Fatalf("text=%q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package pdf
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// Dump formats for --dump-format.
+const (
+	DumpText = "text"
+	DumpJSON = "json"
+)
+
+type pageDump struct {
+	Page     int         `json:"page"`
+	MediaBox [4]float64  `json:"media_box"`
+	Rotate   int64       `json:"rotate"`
+	Blocks   []blockDump `json:"blocks"`
+}
+
+type blockDump struct {
+	Text  string      `json:"text"`
+	BBox  [4]float64  `json:"bbox"`
+	Cell  *[4]float64 `json:"cell,omitempty"`
+	Lines []lineDump  `json:"lines"`
+}
+
+type lineDump struct {
+	Text     string     `json:"text"`
+	BBox     [4]float64 `json:"bbox"`
+	Font     string     `json:"font,omitempty"`
+	FontSize float64    `json:"font_size,omitempty"`
+	Dir      int        `json:"dir,omitempty"`
+	Vertical bool       `json:"vertical,omitempty"`
+}
+
+// dumpJSON encodes pages for Document.JSON. Boxes are [llx, lly, urx, ury] on
+// the page as displayed, after its rotation, with the origin at the bottom
+// left corner of the media box.
+func dumpJSON(pages []extractedPage) ([]byte, error) {
+	out := make([]pageDump, 0, len(pages))
+	for _, p := range pages {
+		page := pageDump{Page: p.number, MediaBox: boxArray(p.mediaBox), Rotate: p.rotate, Blocks: []blockDump{}}
+		for _, b := range p.blocks {
+			block := blockDump{Text: b.Text, BBox: boxArray(b.Box)}
+			if b.Cell != (model.PdfRectangle{}) {
+				cell := boxArray(b.Cell)
+				block.Cell = &cell
+			}
+			for _, l := range b.Lines {
+				block.Lines = append(block.Lines, lineDump{
+					Text:     l.Text,
+					BBox:     boxArray(l.Box),
+					Font:     l.Font,
+					FontSize: round2(l.FontSize),
+					Dir:      l.Dir,
+					Vertical: l.Vertical,
+				})
+			}
+			page.Blocks = append(page.Blocks, block)
+		}
+		out = append(out, page)
+	}
+	data, err := json.MarshalIndent(struct {
+		Pages []pageDump `json:"pages"`
+	}{out}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func boxArray(r model.PdfRectangle) [4]float64 {
+	return [4]float64{round2(r.Llx), round2(r.Lly), round2(r.Urx), round2(r.Ury)}
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
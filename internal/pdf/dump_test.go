@@ -0,0 +1,42 @@
+package pdf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestDumpJSON(t *testing.T) {
+	l := line("Hello", 72.004, 700, 100)
+	l.Font = "Helvetica"
+	pages := []extractedPage{
+		{number: 1, mediaBox: model.PdfRectangle{Urx: 612, Ury: 792}, rotate: 90, blocks: groupParagraphs([]textLine{l})},
+		{number: 3, mediaBox: model.PdfRectangle{Urx: 612, Ury: 792}},
+	}
+	data, err := dumpJSON(pages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Pages []pageDump `json:"pages"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Pages) != 2 || got.Pages[1].Page != 3 || got.Pages[0].Rotate != 90 || got.Pages[0].MediaBox != [4]float64{0, 0, 612, 792} {
+		t.Fatalf("pages=%+v", got.Pages)
+	}
+	if got.Pages[1].Blocks == nil {
+		t.Fatalf("empty page has null blocks: %s", data)
+	}
+	block := got.Pages[0].Blocks[0]
+	if block.Text != "Hello" || len(block.Lines) != 1 {
+		t.Fatalf("block=%+v", block)
+	}
+	want := lineDump{Text: "Hello", BBox: [4]float64{72, 700, 100, 710}, Font: "Helvetica", FontSize: 10}
+	if block.Lines[0] != want {
+		t.Fatalf("line=%+v, want %+v", block.Lines[0], want)
+	}
+}
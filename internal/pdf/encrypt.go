@@ -0,0 +1,110 @@
+package pdf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/core/security"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// WithPassword sets the password used to open encrypted documents.
+func WithPassword(password string) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+// WithPasswordPrompt sets a function that asks for the password when an
+// encrypted document cannot be opened with an empty or configured password.
+func WithPasswordPrompt(prompt func() (string, error)) Option {
+	return func(o *options) {
+		o.prompt = prompt
+	}
+}
+
+// WithKeepEncryption encrypts the translated document with the password it
+// was opened with and the original permissions. The owner password is kept
+// only when the document was opened with it; otherwise a random one is set.
+// By default the output is written without encryption.
+func WithKeepEncryption(keep bool) Option {
+	return func(o *options) {
+		o.keepEncryption = keep
+	}
+}
+
+// decrypt opens an encrypted reader, trying the empty password, the
+// configured password and then the prompt. It returns the password that
+// worked.
+func decrypt(r *model.PdfReader, o options) (string, error) {
+	encrypted, err := r.IsEncrypted()
+	if err != nil || !encrypted {
+		return "", err
+	}
+	ok, err := r.Decrypt([]byte(""))
+	if err != nil || ok {
+		return "", err
+	}
+
+	password := o.password
+	if password == "" {
+		if o.prompt == nil {
+			return "", errors.New("encrypted pdf requires password (set --pdf-password or TRANSLATE_PDF_PASSWORD)")
+		}
+		if password, err = o.prompt(); err != nil {
+			return "", err
+		}
+	}
+	ok, err = r.Decrypt([]byte(password))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("incorrect pdf password")
+	}
+	return password, nil
+}
+
+// keepEncryption returns a writer hook that encrypts the output like the
+// document r, which was opened with password, or nil when r is not
+// encrypted. The output opens with the same password and has the original
+// permissions. Its owner password is password when that is the owner
+// password; otherwise the owner password is unknown, and a random one is
+// set so that the permissions cannot be lifted by anyone.
+func keepEncryption(r *model.PdfReader, password string) (func(*model.PdfWriter) error, error) {
+	encrypted, err := r.IsEncrypted()
+	if err != nil || !encrypted {
+		return nil, err
+	}
+	perms := security.PermOwner
+	if trailer, err := r.GetTrailer(); err == nil {
+		if dict, ok := core.GetDict(core.ResolveReference(trailer.Get("Encrypt"))); ok {
+			if p, ok := core.GetIntVal(dict.Get("P")); ok {
+				perms = security.Permissions(uint32(int32(p)))
+			}
+		}
+	}
+	owner := password
+	if _, granted, err := r.CheckAccessRights([]byte(password)); err != nil || granted != security.PermOwner {
+		if owner, err = randomPassword(); err != nil {
+			return nil, err
+		}
+	}
+	return func(w *model.PdfWriter) error {
+		return w.Encrypt([]byte(password), []byte(owner), &model.EncryptOptions{
+			Permissions: perms,
+			Algorithm:   model.AES_256bit,
+		})
+	}, nil
+}
+
+// randomPassword returns a password that nobody knows.
+func randomPassword() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,130 @@
+package pdf
+
+import (
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// DefaultMinFontSize is the smallest font size auto-fit shrinks
+// translations to.
+const DefaultMinFontSize = 6
+
+// fitted is a translation laid out for a box: its font size and lines.
+type fitted struct {
+	size  float64
+	lines []string
+	// spacing is the distance between lines, relative to size.
+	spacing float64
+	// overflow is set when the text does not fit even at the minimum size.
+	overflow bool
+	// indent is the offset of each line from the start of the box, for
+	// right-aligned text.
+	indent []float64
+}
+
+// fitText lays out text in a box of the given width and height. The font
+// size shrinks from start towards min until the text, wrapped to width with
+// lines spacing*size apart, fits. measure returns the width of a string at
+// font size 1.
+func fitText(text string, width, height, start, min, spacing float64, measure func(string) float64) fitted {
+	text = strings.Join(strings.Fields(text), " ")
+	if min > start {
+		min = start
+	}
+	size := start
+	for {
+		lines := wrapLines(text, width/size, measure)
+		if textHeight(len(lines), size, spacing) <= height*1.01 && fitsWidth(lines, width/size, measure) {
+			return fitted{size: size, lines: lines}
+		}
+		if size <= min {
+			return fitted{size: size, lines: lines, overflow: true}
+		}
+		size = max(size*0.9, min)
+	}
+}
+
+func textHeight(lines int, size, spacing float64) float64 {
+	if lines == 0 {
+		return 0
+	}
+	return size + float64(lines-1)*size*spacing
+}
+
+func fitsWidth(lines []string, width float64, measure func(string) float64) bool {
+	for _, l := range lines {
+		if measure(l) > width*1.01 {
+			return false
+		}
+	}
+	return true
+}
+
+// wrapLines breaks text greedily into lines no wider than width. Lines break
+// at spaces and between CJK characters; a word wider than width gets a line
+// of its own.
+func wrapLines(text string, width float64, measure func(string) float64) []string {
+	var lines []string
+	var line strings.Builder
+	for _, tok := range breakTokens(text) {
+		candidate := line.String() + tok
+		if line.Len() > 0 && measure(strings.TrimRight(candidate, " ")) > width {
+			lines = append(lines, strings.TrimRight(line.String(), " "))
+			line.Reset()
+			tok = strings.TrimLeft(tok, " ")
+		}
+		line.WriteString(tok)
+	}
+	if s := strings.TrimRight(line.String(), " "); s != "" {
+		lines = append(lines, s)
+	}
+	return lines
+}
+
+// breakTokens splits text into the units wrapLines keeps together: a word
+// with its trailing space, or a single CJK character.
+func breakTokens(text string) []string {
+	var tokens []string
+	start := 0
+	for i, r := range text {
+		switch {
+		case isCJK(r):
+			if i > start {
+				tokens = append(tokens, text[start:i])
+			}
+			tokens = append(tokens, string(r))
+			start = i + len(string(r))
+		case r == ' ' && i+1 < len(text) && text[i+1] != ' ':
+			tokens = append(tokens, text[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(text) {
+		tokens = append(tokens, text[start:])
+	}
+	return tokens
+}
+
+// fontMeasure returns a measure function for fitText using the glyph widths
+// of font. Glyphs the font lacks count as half an em, or a full em when
+// they are wide.
+func fontMeasure(font *model.PdfFont) func(string) float64 {
+	return func(s string) float64 {
+		w := 0.0
+		for _, r := range s {
+			if font != nil {
+				if m, ok := font.GetRuneMetrics(r); ok && m.Wx > 0 {
+					w += m.Wx / 1000
+					continue
+				}
+			}
+			if isCJK(r) {
+				w++
+			} else {
+				w += 0.5
+			}
+		}
+		return w
+	}
+}
@@ -0,0 +1,70 @@
+package pdf
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf8"
+)
+
+// monospace measures every character as half an em.
+func monospace(s string) float64 {
+	return float64(utf8.RuneCountInString(s)) * 0.5
+}
+
+func TestFitTextKeepsSizeWhenItFits(t *testing.T) {
+	fit := fitText("short text", 100, 10, 10, 6, 1.2, monospace)
+	if fit.overflow || fit.size != 10 || !reflect.DeepEqual(fit.lines, []string{"short text"}) {
+		t.Fatalf("fit=%+v", fit)
+	}
+}
+
+func TestFitTextShrinks(t *testing.T) {
+	// 24 characters are 120pt wide at 10pt; the box is 100pt wide.
+	fit := fitText("abcdefghijklmnopqrstuvwx", 100, 10, 10, 6, 1.2, monospace)
+	if fit.overflow || len(fit.lines) != 1 || fit.size >= 10 || fit.size < 6 {
+		t.Fatalf("fit=%+v", fit)
+	}
+	if w := monospace(fit.lines[0]) * fit.size; w > 101 {
+		t.Fatalf("width=%v", w)
+	}
+}
+
+func TestFitTextWrapsIntoTallBox(t *testing.T) {
+	fit := fitText("one two three four five six", 50, 40, 10, 6, 1.2, monospace)
+	if fit.overflow || fit.size != 10 {
+		t.Fatalf("fit=%+v", fit)
+	}
+	want := []string{"one two", "three four", "five six"}
+	if !reflect.DeepEqual(fit.lines, want) {
+		t.Fatalf("lines=%q", fit.lines)
+	}
+}
+
+func TestFitTextOverflow(t *testing.T) {
+	fit := fitText("a very long translation that cannot possibly fit", 20, 10, 10, 6, 1.2, monospace)
+	if !fit.overflow || fit.size != 6 {
+		t.Fatalf("fit=%+v", fit)
+	}
+}
+
+func TestWrapLinesCJK(t *testing.T) {
+	lines := wrapLines("日本語の文章です", 2, monospace)
+	want := []string{"日本語の", "文章です"}
+	if !reflect.DeepEqual(lines, want) {
+		t.Fatalf("lines=%q", lines)
+	}
+}
+
+func TestRoomBelow(t *testing.T) {
+	blocks := []textBlock{
+		{Lines: []textLine{line("a", 72, 700, 300)}, Box: line("a", 72, 700, 300).Box},
+		{Lines: []textLine{line("b", 400, 660, 500)}, Box: line("b", 400, 660, 500).Box},
+		{Lines: []textLine{line("c", 100, 640, 200)}, Box: line("c", 100, 640, 200).Box},
+	}
+	if got := roomBelow(blocks, 0, 0); got != 60 {
+		t.Fatalf("room=%v", got)
+	}
+	if got := roomBelow(blocks, 2, 36); got != 614 {
+		t.Fatalf("room=%v", got)
+	}
+}
@@ -0,0 +1,191 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/unidoc/unipdf/v4/model"
+	"golang.org/x/image/font/sfnt"
+)
+
+// WithStyleFonts sets TTF files for bold, italic and bold italic text. An
+// empty path falls back to the closest available style: bold italic to bold,
+// and italic to the regular font. Bold text without a bold font is drawn
+// with a thin outline.
+func WithStyleFonts(bold, italic, boldItalic string) Option {
+	return func(o *options) {
+		o.boldFont, o.italicFont, o.boldItalicFont = bold, italic, boldItalic
+	}
+}
+
+// WithFallbackFonts sets TTF files used, in order, for characters the
+// overlay font does not have.
+func WithFallbackFonts(paths []string) Option {
+	return func(o *options) {
+		o.fallbackFonts = paths
+	}
+}
+
+// overlayFont is a font for drawing with the character map used to check
+// which characters it covers.
+type overlayFont struct {
+	pdf  *model.PdfFont
+	cmap *sfnt.Font
+	buf  sfnt.Buffer
+}
+
+// covers reports whether f has a glyph for r. The built-in Helvetica
+// covers the characters of WinAnsiEncoding, which it is drawn with.
+func (f *overlayFont) covers(r rune) bool {
+	if f.cmap == nil {
+		_, ok := f.pdf.Encoder().RuneToCharcode(r)
+		return ok
+	}
+	g, err := f.cmap.GlyphIndex(&f.buf, r)
+	return err == nil && g != 0
+}
+
+func loadOverlayFont(fontPath string) (*overlayFont, error) {
+	if strings.TrimSpace(fontPath) == "" {
+		font, err := model.NewStandard14Font("Helvetica")
+		if err != nil {
+			return nil, err
+		}
+		return &overlayFont{pdf: font}, nil
+	}
+	data, err := os.ReadFile(fontPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("pdf font not found: %s", fontPath)
+		}
+		return nil, err
+	}
+	cmap, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("pdf font %s: %w", fontPath, err)
+	}
+	font, err := model.NewCompositePdfFontFromTTF(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("pdf font %s: %w", fontPath, err)
+	}
+	return &overlayFont{pdf: font, cmap: cmap}, nil
+}
+
+// fontSet is the overlay font family and its fallback chain.
+type fontSet struct {
+	regular    *overlayFont
+	bold       *overlayFont
+	italic     *overlayFont
+	boldItalic *overlayFont
+	fallbacks  []*overlayFont
+}
+
+func loadFontSet(regular string, o options) (fontSet, error) {
+	var fonts fontSet
+	var err error
+	if fonts.regular, err = loadOverlayFont(regular); err != nil {
+		return fontSet{}, err
+	}
+	for _, f := range []struct {
+		path string
+		font **overlayFont
+	}{{o.boldFont, &fonts.bold}, {o.italicFont, &fonts.italic}, {o.boldItalicFont, &fonts.boldItalic}} {
+		if strings.TrimSpace(f.path) == "" {
+			continue
+		}
+		if *f.font, err = loadOverlayFont(f.path); err != nil {
+			return fontSet{}, err
+		}
+	}
+	for _, path := range o.fallbackFonts {
+		if strings.TrimSpace(path) == "" {
+			continue
+		}
+		font, err := loadOverlayFont(path)
+		if err != nil {
+			return fontSet{}, err
+		}
+		fonts.fallbacks = append(fonts.fallbacks, font)
+	}
+	return fonts, nil
+}
+
+// pick returns the font for a line's style and whether bold has to be
+// simulated.
+func (f fontSet) pick(bold, italic bool) (*overlayFont, bool) {
+	switch {
+	case bold && italic && f.boldItalic != nil:
+		return f.boldItalic, false
+	case bold && f.bold != nil:
+		return f.bold, false
+	case italic && !bold && f.italic != nil:
+		return f.italic, false
+	}
+	return f.regular, bold
+}
+
+// fontRun is a stretch of text drawn in one font.
+type fontRun struct {
+	text string
+	font *overlayFont
+}
+
+// runs splits text into runs of the first font, starting with primary and
+// then the fallbacks, that covers each character. Spaces and marks stay in
+// the current run. Characters no font covers are drawn in primary and
+// returned as missing.
+func (f fontSet) runs(text string, primary *overlayFont) ([]fontRun, []rune) {
+	var runs []fontRun
+	var missing []rune
+	var b strings.Builder
+	current := primary
+	for _, r := range text {
+		font := current
+		if !unicode.IsSpace(r) && !unicode.Is(unicode.Mn, r) {
+			font = f.cover(r, primary)
+			if font == nil {
+				missing = append(missing, r)
+				font = primary
+			}
+		}
+		if font != current && b.Len() > 0 {
+			runs = append(runs, fontRun{text: b.String(), font: current})
+			b.Reset()
+		}
+		current = font
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		runs = append(runs, fontRun{text: b.String(), font: current})
+	}
+	return runs, missing
+}
+
+func (f fontSet) cover(r rune, primary *overlayFont) *overlayFont {
+	if primary.covers(r) {
+		return primary
+	}
+	for _, font := range f.fallbacks {
+		if font.covers(r) {
+			return font
+		}
+	}
+	return nil
+}
+
+// measure returns a measure function for fitText that uses the font of
+// each run.
+func (f fontSet) measure(primary *overlayFont) func(string) float64 {
+	return func(s string) float64 {
+		runs, _ := f.runs(s, primary)
+		w := 0.0
+		for _, run := range runs {
+			w += fontMeasure(run.font.pdf)(run.text)
+		}
+		return w
+	}
+}
@@ -0,0 +1,85 @@
+package pdf
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/model"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+)
+
+func goFont(t *testing.T) *overlayFont {
+	t.Helper()
+	cmap, err := sfnt.Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := model.NewCompositePdfFontFromTTF(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &overlayFont{pdf: font, cmap: cmap}
+}
+
+func TestFontSetPick(t *testing.T) {
+	regular, bold := &overlayFont{}, &overlayFont{}
+	fonts := fontSet{regular: regular, bold: bold}
+	if f, fake := fonts.pick(true, true); f != bold || fake {
+		t.Fatal("bold italic does not fall back to bold")
+	}
+	if f, fake := fonts.pick(false, true); f != regular || fake {
+		t.Fatal("italic does not fall back to regular")
+	}
+	if f, fake := (fontSet{regular: regular}).pick(true, false); f != regular || !fake {
+		t.Fatal("missing bold font is not simulated")
+	}
+}
+
+func TestHelveticaCoversWinAnsi(t *testing.T) {
+	helvetica, err := loadOverlayFont("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range "aé‘’“”–—€…" {
+		if !helvetica.covers(r) {
+			t.Errorf("%q not covered", r)
+		}
+	}
+	for _, r := range "\u0080\u009fŁあ" {
+		if helvetica.covers(r) {
+			t.Errorf("%U covered", r)
+		}
+	}
+}
+
+func TestFontSetRuns(t *testing.T) {
+	latin, err := loadOverlayFont("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	greek := goFont(t)
+	fonts := fontSet{regular: latin, fallbacks: []*overlayFont{greek}}
+
+	runs, missing := fonts.runs("“Café” ΩΨ and あ", latin)
+	type run struct {
+		text string
+		font *overlayFont
+	}
+	var got []run
+	for _, r := range runs {
+		got = append(got, run{r.text, r.font})
+	}
+	want := []run{{"“Café” ", latin}, {"ΩΨ ", greek}, {"and あ", latin}}
+	if len(got) != len(want) {
+		t.Fatalf("runs=%v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("run %d=%q, want %q", i, got[i].text, want[i].text)
+		}
+	}
+	if string(missing) != "あ" {
+		t.Fatalf("missing=%q", string(missing))
+	}
+}
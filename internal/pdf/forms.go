@@ -0,0 +1,86 @@
+package pdf
+
+import (
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// formTexts returns the user-visible text of the form fields that
+// translateForm translates: tooltips and choice display values.
+func formTexts(form *model.PdfAcroForm) []string {
+	var out []string
+	for _, field := range form.AllFields() {
+		if field.TU != nil {
+			out = append(out, field.TU.Decoded())
+		}
+		if choice, ok := field.GetContext().(*model.PdfFieldChoice); ok && choice.Opt != nil {
+			for _, opt := range choice.Opt.Elements() {
+				if _, display, ok := choiceOption(opt); ok {
+					out = append(out, display)
+				}
+			}
+		}
+	}
+	return nonBlank(out)
+}
+
+// choiceOption returns the export value and the display text of an entry
+// of a choice field's /Opt: a string that is both, or an array of the
+// export value and the display text. The export value is returned as it is
+// stored, so that it keeps matching the field's value byte for byte.
+func choiceOption(opt core.PdfObject) (core.PdfObject, string, bool) {
+	opt = core.ResolveReference(opt)
+	if s, ok := core.GetString(opt); ok {
+		return s, s.Decoded(), true
+	}
+	if arr, ok := core.GetArray(opt); ok && arr.Len() == 2 {
+		if display, ok := core.GetString(core.ResolveReference(arr.Get(1))); ok {
+			return arr.Get(0), display.Decoded(), true
+		}
+	}
+	return nil, "", false
+}
+
+// translateForm translates the tooltips (/TU) of the fields of form and the
+// display text of choice options. Field names, values and export values are
+// left unchanged so that the form can still be filled and its data read by
+// the same tools; options that used one string for both become pairs of
+// the original export value and the translated display text. Viewers are
+// asked to redraw fields, whose appearances show the old text.
+func translateForm(form *model.PdfAcroForm, translate func(string) (string, error)) error {
+	changed := false
+	for _, field := range form.AllFields() {
+		if field.TU != nil && strings.TrimSpace(field.TU.Decoded()) != "" {
+			out, err := translate(field.TU.Decoded())
+			if err != nil {
+				return err
+			}
+			field.TU = core.MakeEncodedString(out, true)
+		}
+		choice, ok := field.GetContext().(*model.PdfFieldChoice)
+		if !ok || choice.Opt == nil {
+			continue
+		}
+		for i, opt := range choice.Opt.Elements() {
+			export, display, ok := choiceOption(opt)
+			if !ok || strings.TrimSpace(display) == "" {
+				continue
+			}
+			out, err := translate(display)
+			if err != nil {
+				return err
+			}
+			pair := core.MakeArray(export, core.MakeEncodedString(out, true))
+			if err := choice.Opt.Set(i, pair); err != nil {
+				return err
+			}
+			changed = true
+		}
+	}
+	if changed {
+		form.NeedAppearances = core.MakeBool(true)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestTranslateForm(t *testing.T) {
+	name := model.NewPdfField()
+	name.T = core.MakeString("name")
+	name.TU = core.MakeString("Your full name")
+	name.V = core.MakeString("Jane")
+
+	country := model.NewPdfField()
+	country.T = core.MakeString("country")
+	country.V = core.MakeString("jp")
+	choice := &model.PdfFieldChoice{PdfField: country, Opt: core.MakeArray(
+		core.MakeArray(core.MakeString("jp"), core.MakeString("Japan")),
+		core.MakeString("Other"),
+	)}
+	country.SetContext(choice)
+
+	form := model.NewPdfAcroForm()
+	form.Fields = &[]*model.PdfField{name, country}
+	if got := strings.Join(formTexts(form), "|"); got != "Your full name|Japan|Other" {
+		t.Fatalf("texts=%q", got)
+	}
+
+	if err := translateForm(form, upper); err != nil {
+		t.Fatal(err)
+	}
+	if name.TU.Decoded() != "YOUR FULL NAME" || name.T.Decoded() != "name" {
+		t.Fatalf("name field: TU=%q T=%q", name.TU.Decoded(), name.T.Decoded())
+	}
+	if v, _ := core.GetString(name.V); v.Decoded() != "Jane" {
+		t.Fatalf("value=%q", v.Decoded())
+	}
+	var opts []string
+	for _, opt := range choice.Opt.Elements() {
+		export, display, _ := choiceOption(opt)
+		s, _ := core.GetString(export)
+		opts = append(opts, s.Decoded()+"="+display)
+	}
+	if got := strings.Join(opts, ","); got != "jp=JAPAN,Other=OTHER" {
+		t.Fatalf("options=%q", got)
+	}
+	if form.NeedAppearances == nil || !bool(*form.NeedAppearances) {
+		t.Fatal("NeedAppearances not set")
+	}
+}
+
+func TestFormKeptOnlyWithOwnPages(t *testing.T) {
+	reader := &model.PdfReader{AcroForm: model.NewPdfAcroForm()}
+	for layout, keep := range map[string]bool{LayoutOverlay: true, LayoutSideBySide: false, LayoutInterleavePages: false} {
+		d := &Document{reader: reader, opts: newOptions([]Option{WithLayout(layout)})}
+		if got := d.form() != nil; got != keep {
+			t.Fatalf("%s: form kept=%v", layout, got)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package pdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// pageBlocks returns the paragraphs of a page with mediaBox and rotation
+// rotate in reading order, with boxes on the page as displayed. The ruling
+// lines in rules, also as displayed, mark out tables, whose cells become
+// blocks of their own.
+func pageBlocks(marks []extractor.TextMark, mediaBox model.PdfRectangle, rotate int, rules []model.PdfRectangle) []textBlock {
+	marks = displayMarks(marks, mediaBox, rotate)
+	tables := findTables(rules, marks)
+	lines := readingOrder(groupLines(splitCells(marks, tables)))
+	return groupParagraphs(markCells(lines, tables))
+}
+
+// readingOrder sorts lines into reading order with a recursive XY-cut: the
+// lines are split at the widest empty band, either a vertical gutter between
+// columns (left column first, or right first for vertical writing) or a
+// horizontal gap (top first), and each side is ordered the same way. Lines
+// that cannot be separated keep extraction order.
+func readingOrder(lines []textLine) []textLine {
+	if len(lines) < 2 {
+		return lines
+	}
+	xGap, left, right := widestGap(lines, func(l textLine) (float64, float64) { return l.Box.Llx, l.Box.Urx })
+	yGap, top, bottom := widestGap(lines, func(l textLine) (float64, float64) { return -l.Box.Ury, -l.Box.Lly })
+	switch {
+	case xGap >= columnGap(lines) && xGap >= yGap:
+		if allVertical(lines) {
+			return append(readingOrder(right), readingOrder(left)...)
+		}
+		return append(readingOrder(left), readingOrder(right)...)
+	case yGap > 0:
+		return append(readingOrder(top), readingOrder(bottom)...)
+	}
+	return lines
+}
+
+func allVertical(lines []textLine) bool {
+	for _, l := range lines {
+		if !l.Vertical {
+			return false
+		}
+	}
+	return true
+}
+
+// widestGap projects lines onto an axis with span and returns the widest
+// empty interval, with the lines before and after it in their original
+// order.
+func widestGap(lines []textLine, span func(textLine) (float64, float64)) (float64, []textLine, []textLine) {
+	sorted := append([]textLine(nil), lines...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := span(sorted[i])
+		b, _ := span(sorted[j])
+		return a < b
+	})
+	best, at := 0.0, 0.0
+	_, end := span(sorted[0])
+	for _, l := range sorted[1:] {
+		start, stop := span(l)
+		if gap := start - end; gap > best {
+			best, at = gap, start
+		}
+		end = maxFloat(end, stop)
+	}
+	if best <= 0 {
+		return 0, nil, nil
+	}
+	var before, after []textLine
+	for _, l := range lines {
+		if start, _ := span(l); start < at {
+			before = append(before, l)
+		} else {
+			after = append(after, l)
+		}
+	}
+	return best, before, after
+}
+
+// columnGap is the narrowest gutter that separates columns: the median line
+// height, so that ragged line ends and indents do not count.
+func columnGap(lines []textLine) float64 {
+	heights := make([]float64, 0, len(lines))
+	for _, l := range lines {
+		box := readingBox(l)
+		heights = append(heights, box.Ury-box.Lly)
+	}
+	sort.Float64s(heights)
+	return maxFloat(heights[len(heights)/2], 4)
+}
+
+// formatBlocks renders the blocks of a page for --dump-extracted, each with
+// its position so the layout analysis can be checked against the page.
+func formatBlocks(blocks []textBlock) string {
+	var b strings.Builder
+	for i, block := range blocks {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		box := block.Box
+		fmt.Fprintf(&b, "--- block %d (x=%.0f y=%.0f w=%.0f h=%.0f) ---\n", i+1, box.Llx, box.Lly, box.Urx-box.Llx, box.Ury-box.Lly)
+		b.WriteString(block.Text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
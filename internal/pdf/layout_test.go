@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/creator"
+)
+
+func TestOpenRejectsUnknownLayout(t *testing.T) {
+	_, err := Open("in.pdf", "", WithLayout("spread"))
+	if err == nil || !strings.Contains(err.Error(), "unknown pdf layout") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// pageSizes returns the size of every page of c after finalizing it.
+func pageSizes(t *testing.T, c *creator.Creator) []string {
+	t.Helper()
+	var sizes []string
+	c.PageFinalize(func(args creator.PageFinalizeFunctionArgs) error {
+		sizes = append(sizes, fmt.Sprintf("%gx%g", args.PageWidth, args.PageHeight))
+		return nil
+	})
+	if err := c.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	return sizes
+}
+
+func TestSideBySidePage(t *testing.T) {
+	c := creator.New()
+	p := extractedPage{page: testPage(t, "BT (Original) Tj ET"), width: 612, height: 792}
+	p.mediaBox = *p.page.MediaBox
+	o := newOptions([]Option{WithLayout(LayoutSideBySide)})
+	if _, err := addTranslatedPage(context.Background(), nil, c, p, o, "en", "ja", 0, nil, fontSet{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pageSizes(t, c); !reflect.DeepEqual(got, []string{"1224x792"}) {
+		t.Fatalf("pages=%v", got)
+	}
+
+	// The translated copy goes on the right half of the spread.
+	c = creator.New()
+	left, right := creator.NewBlock(612, 792), creator.NewBlock(612, 792)
+	spread, err := addSpread(c, left, right, 612, 792)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if box := spread.MediaBox; box.Width() != 1224 || box.Height() != 792 {
+		t.Fatalf("mediabox=%+v", box)
+	}
+	content, err := spread.GetAllContentStreams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(content, "1 0 0 1 612 0 cm") {
+		t.Fatalf("content=%q", content)
+	}
+}
+
+func TestInterleavedPages(t *testing.T) {
+	c := creator.New()
+	p := extractedPage{page: testPage(t, "BT (Original) Tj ET"), width: 612, height: 792}
+	p.mediaBox = *p.page.MediaBox
+	o := newOptions([]Option{WithLayout(LayoutInterleavePages)})
+	if _, err := addTranslatedPage(context.Background(), nil, c, p, o, "en", "ja", 0, nil, fontSet{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := pageSizes(t, c); !reflect.DeepEqual(got, []string{"612x792", "612x792"}) {
+		t.Fatalf("pages=%v", got)
+	}
+}
+
+func TestOutputPages(t *testing.T) {
+	// Pages 2 and 4 of five are selected.
+	pages := []extractedPage{{number: 2}, {number: 4}}
+	cases := []struct {
+		opts []Option
+		want map[int64]int64
+	}{
+		{nil, map[int64]int64{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}},
+		{[]Option{WithLayout(LayoutSideBySide)}, map[int64]int64{0: 0, 1: 1, 2: 2, 3: 3, 4: 4}},
+		// Outline items point at the translated copy after the original.
+		{[]Option{WithLayout(LayoutInterleavePages)}, map[int64]int64{0: 0, 1: 2, 2: 3, 3: 5, 4: 6}},
+		{[]Option{WithLayout(LayoutInterleavePages), WithSelectedPagesOnly(true)}, map[int64]int64{1: 1, 3: 3}},
+		{[]Option{WithSelectedPagesOnly(true)}, map[int64]int64{1: 0, 3: 1}},
+	}
+	for _, c := range cases {
+		d := &Document{count: 5, pages: pages, opts: newOptions(c.opts)}
+		if got := d.outputPages(); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s selectedOnly=%v: got %v, want %v", d.opts.layout, d.opts.selectedOnly, got, c.want)
+		}
+	}
+}
+
+func lineTexts(lines []textLine) []string {
+	var out []string
+	for _, l := range lines {
+		out = append(out, l.Text)
+	}
+	return out
+}
+
+func TestReadingOrderTwoColumns(t *testing.T) {
+	// Extraction order alternates between the columns row by row.
+	lines := []textLine{
+		line("Title", 72, 740, 540),
+		line("L1", 72, 700, 290),
+		line("R1", 320, 700, 540),
+		line("L2", 72, 688, 290),
+		line("R2", 320, 688, 540),
+		line("L3", 72, 676, 290),
+		line("R3", 320, 676, 540),
+		line("7", 300, 40, 312),
+	}
+	got := strings.Join(lineTexts(readingOrder(lines)), " ")
+	if want := "Title L1 L2 L3 R1 R2 R3 7"; got != want {
+		t.Fatalf("order=%q, want %q", got, want)
+	}
+}
+
+func TestReadingOrderSingleColumnKeepsOrder(t *testing.T) {
+	lines := []textLine{
+		line("one", 72, 700, 500),
+		line("two", 90, 688, 300),
+		line("three", 72, 676, 400),
+	}
+	got := strings.Join(lineTexts(readingOrder(lines)), " ")
+	if got != "one two three" {
+		t.Fatalf("order=%q", got)
+	}
+}
+
+func TestFormatBlocks(t *testing.T) {
+	blocks := groupParagraphs([]textLine{line("Hello", 72, 700, 100)})
+	want := "--- block 1 (x=72 y=700 w=28 h=10) ---\nHello\n"
+	if got := formatBlocks(blocks); got != want {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -1,30 +1,54 @@
 package pdf
 
 import (
+	"image/color"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/unidoc/unipdf/v4/extractor"
 	"github.com/unidoc/unipdf/v4/model"
 )
 
+// columnSplit is the horizontal gap, in multiples of the glyph height, that
+// splits marks on one baseline into separate lines.
+const columnSplit = 2.5
+
 type textLine struct {
-	Text string
-	Box  model.PdfRectangle
+	Text     string
+	Box      model.PdfRectangle
+	FontSize float64
+	// Font is the base font name of the line's first glyph.
+	Font string
+	// Color, Bold and Italic are the style of most of the line's glyphs.
+	Color  color.Color
+	Bold   bool
+	Italic bool
+	// Dir is the direction the text runs on the displayed page, in degrees
+	// anticlockwise: 0 for ordinary text, 90 for text running upwards.
+	Dir int
+	// Vertical is set for vertical writing: upright characters stacked in
+	// a column, which reads downwards with Dir 270.
+	Vertical bool
+	// Cell is the box of the table cell the line is in, or the zero box.
+	Cell model.PdfRectangle
 }
 
 func groupLines(marks []extractor.TextMark) []textLine {
 	lines := make([]textLine, 0)
 	var current textLine
+	var style styleCounter
 	var hasBox bool
 
 	flush := func() {
-		if strings.TrimSpace(current.Text) == "" {
-			current = textLine{}
-			hasBox = false
-			return
+		if strings.TrimSpace(current.Text) != "" {
+			style.apply(&current)
+			if current.Dir == 0 && isVerticalColumn(current) {
+				current.Dir, current.Vertical = 270, true
+			}
+			lines = append(lines, current)
 		}
-		lines = append(lines, current)
 		current = textLine{}
+		style = styleCounter{}
 		hasBox = false
 	}
 
@@ -38,16 +62,87 @@ func groupLines(marks []extractor.TextMark) []textLine {
 			continue
 		}
 
+		if hasBox && m.Orientation != current.Dir {
+			current.Text = strings.TrimRight(current.Text, " ")
+			flush()
+		}
+		box := rotateBox(m.BBox, m.Orientation)
+		if hasBox && box.Llx-rotateBox(current.Box, current.Dir).Urx > columnSplit*(box.Ury-box.Lly) {
+			// A wide gap on the same baseline separates columns or cells.
+			current.Text = strings.TrimRight(current.Text, " ")
+			flush()
+		}
 		current.Text += m.Text
+		current.FontSize = maxFloat(current.FontSize, m.FontSize)
+		if current.Font == "" && m.Font != nil {
+			current.Font = m.Font.BaseFont()
+		}
+		bold, italic := fontStyle(m.Font)
+		style.add(glyphStyle{color: m.FillColor, bold: bold, italic: italic}, utf8.RuneCountInString(m.Text))
 		if !hasBox {
 			current.Box = m.BBox
+			current.Dir = m.Orientation
 			hasBox = true
 		} else {
 			current.Box = unionBox(current.Box, m.BBox)
 		}
 	}
 	flush()
-	return lines
+	return stackColumns(lines)
+}
+
+// isVerticalColumn reports whether line is a column of upright characters,
+// far taller than it is wide.
+func isVerticalColumn(line textLine) bool {
+	w, h := line.Box.Urx-line.Box.Llx, line.Box.Ury-line.Box.Lly
+	return utf8.RuneCountInString(strings.TrimSpace(line.Text)) >= 2 && h > 2*w
+}
+
+// stackColumns joins runs of lines of one or two CJK characters that sit
+// directly below each other into vertical lines, for extraction that breaks
+// vertical writing after every character.
+func stackColumns(lines []textLine) []textLine {
+	out := make([]textLine, 0, len(lines))
+	for _, line := range lines {
+		if n := len(out); n > 0 && stacksBelow(out[n-1], line) {
+			last := &out[n-1]
+			last.Text += strings.TrimSpace(line.Text)
+			last.Box = unionBox(last.Box, line.Box)
+			last.Dir, last.Vertical = 270, true
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// stacksBelow reports whether line continues the column above: both are
+// short upright CJK text and line starts right below above, with at most
+// the gap between characters.
+func stacksBelow(above, line textLine) bool {
+	if !shortCJK(line) || !(above.Vertical || shortCJK(above)) {
+		return false
+	}
+	h := line.Box.Ury - line.Box.Lly
+	w := line.Box.Urx - line.Box.Llx
+	overlap := minFloat(above.Box.Urx, line.Box.Urx) - maxFloat(above.Box.Llx, line.Box.Llx)
+	gap := above.Box.Lly - line.Box.Ury
+	return overlap >= 0.8*minFloat(w, above.Box.Urx-above.Box.Llx) && gap >= -0.2*h && gap <= 0.25*h
+}
+
+// shortCJK reports whether line is one or two upright CJK characters.
+func shortCJK(line textLine) bool {
+	text := strings.TrimSpace(line.Text)
+	h := line.Box.Ury - line.Box.Lly
+	if line.Dir != 0 || line.Vertical || h <= 0 || line.Box.Urx-line.Box.Llx > 2.2*h || utf8.RuneCountInString(text) > 2 {
+		return false
+	}
+	for _, r := range text {
+		if !isCJK(r) {
+			return false
+		}
+	}
+	return true
 }
 
 func unionBox(a, b model.PdfRectangle) model.PdfRectangle {
@@ -27,3 +27,16 @@ func TestGroupLines(t *testing.T) {
 		t.Fatalf("line1 text=%q", lines[1].Text)
 	}
 }
+
+func TestGroupLinesSplitsAtGutter(t *testing.T) {
+	marks := []extractor.TextMark{
+		{Text: "left", BBox: model.PdfRectangle{Llx: 10, Lly: 10, Urx: 30, Ury: 20}},
+		{Text: " ", Meta: true},
+		{Text: "right", BBox: model.PdfRectangle{Llx: 80, Lly: 10, Urx: 100, Ury: 20}},
+	}
+
+	lines := groupLines(marks)
+	if len(lines) != 2 || lines[0].Text != "left" || lines[1].Text != "right" {
+		t.Fatalf("lines=%+v", lines)
+	}
+}
@@ -0,0 +1,211 @@
+package pdf
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// docMetadata is the document-level text translated along with the pages:
+// the outline, the title and subject of the document information and the
+// same fields of the XMP metadata.
+type docMetadata struct {
+	outline []*outlineEntry
+	info    *model.PdfInfo
+	xmp     []byte
+}
+
+// xmpField matches the XMP title and description, each a list of
+// alternative texts by language.
+var (
+	xmpField = regexp.MustCompile(`(?s)<dc:(title|description)>.*?</dc:(?:title|description)>`)
+	xmpItem  = regexp.MustCompile(`(?s)(<rdf:li\b[^>]*>)(.*?)(</rdf:li>)`)
+)
+
+// readMetadata reads the document-level text of r. Each part is best
+// effort: a document whose outline or metadata cannot be read is still
+// translated without it.
+func readMetadata(r *model.PdfReader) docMetadata {
+	var m docMetadata
+	m.outline = readOutline(r)
+	if info, err := r.GetPdfInfo(); err == nil {
+		m.info = info
+	}
+	if obj, ok := r.GetCatalogMetadata(); ok {
+		if stream, ok := core.GetStream(core.ResolveReference(obj)); ok {
+			if data, err := core.DecodeStream(stream); err == nil {
+				m.xmp = data
+			}
+		}
+	}
+	return m
+}
+
+// texts returns the strings Translate translates, for counting requests.
+func (m docMetadata) texts() []string {
+	var out []string
+	out = append(out, outlineTitles(m.outline)...)
+	if m.info != nil {
+		for _, s := range []*core.PdfObjectString{m.info.Title, m.info.Subject} {
+			if s != nil {
+				out = append(out, s.Decoded())
+			}
+		}
+	}
+	for _, field := range xmpField.FindAll(m.xmp, -1) {
+		for _, item := range xmpItem.FindAllSubmatch(field, -1) {
+			out = append(out, html.UnescapeString(string(item[2])))
+		}
+	}
+	return nonBlank(out)
+}
+
+func nonBlank(texts []string) []string {
+	out := texts[:0]
+	for _, t := range texts {
+		if strings.TrimSpace(t) != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// translate translates the metadata in place with translate. Outline items
+// that go to a page are pointed at the output pages: pages maps input page
+// indexes to output page indexes, both from 0, and items whose page is left
+// out are dropped in favour of their children.
+func (m *docMetadata) translate(translate func(string) (string, error), pages map[int64]int64) error {
+	if err := translateOutline(m.outline, translate); err != nil {
+		return err
+	}
+	m.outline = remapOutline(m.outline, pages)
+	if m.info != nil {
+		for _, s := range []**core.PdfObjectString{&m.info.Title, &m.info.Subject} {
+			if *s == nil || strings.TrimSpace((*s).Decoded()) == "" {
+				continue
+			}
+			out, err := translate((*s).Decoded())
+			if err != nil {
+				return err
+			}
+			*s = core.MakeEncodedString(out, true)
+		}
+	}
+	if m.xmp != nil {
+		xmp, err := translateXMP(m.xmp, translate)
+		if err != nil {
+			return err
+		}
+		m.xmp = xmp
+	}
+	return nil
+}
+
+// translateXMP translates the alternative texts of the XMP title and
+// description, leaving the rest of the packet untouched.
+func translateXMP(xmp []byte, translate func(string) (string, error)) ([]byte, error) {
+	var failed error
+	out := xmpField.ReplaceAllFunc(xmp, func(field []byte) []byte {
+		return xmpItem.ReplaceAllFunc(field, func(item []byte) []byte {
+			parts := xmpItem.FindSubmatch(item)
+			text := html.UnescapeString(string(parts[2]))
+			if failed != nil || strings.TrimSpace(text) == "" {
+				return item
+			}
+			translated, err := translate(text)
+			if err != nil {
+				failed = err
+				return item
+			}
+			return []byte(string(parts[1]) + html.EscapeString(translated) + string(parts[3]))
+		})
+	})
+	return out, failed
+}
+
+// write stores the document information and XMP metadata in the output.
+// The outline goes through the creator, which otherwise writes its own.
+func (m docMetadata) write(w *model.PdfWriter) error {
+	if m.info != nil {
+		w.SetDocInfo(m.info)
+	}
+	if m.xmp != nil {
+		stream, err := core.MakeStream(m.xmp, nil)
+		if err != nil {
+			return err
+		}
+		stream.PdfObjectDictionary.Set("Type", core.MakeName("Metadata"))
+		stream.PdfObjectDictionary.Set("Subtype", core.MakeName("XML"))
+		return w.SetCatalogMetadata(stream)
+	}
+	return nil
+}
+
+// pageAnnotations returns the annotations of page with text in Contents:
+// link descriptions, comments and other notes. Pop-ups, which show the
+// text of their parent, are left out.
+func pageAnnotations(page *model.PdfPage) []*model.PdfAnnotation {
+	annots, err := page.GetAnnotations()
+	if err != nil {
+		return nil
+	}
+	var out []*model.PdfAnnotation
+	for _, a := range annots {
+		if _, popup := a.GetContext().(*model.PdfAnnotationPopup); popup {
+			continue
+		}
+		if s, ok := core.GetString(a.Contents); ok && strings.TrimSpace(s.Decoded()) != "" {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// translateAnnotations translates the Contents of annots. Rich text
+// contents, which viewers prefer, are removed so that the translation is
+// shown.
+func translateAnnotations(annots []*model.PdfAnnotation, translate func(string) (string, error)) error {
+	for _, a := range annots {
+		s, _ := core.GetString(a.Contents)
+		out, err := translate(s.Decoded())
+		if err != nil {
+			return err
+		}
+		a.Contents = core.MakeEncodedString(out, true)
+		if markup := annotationMarkup(a); markup != nil {
+			markup.RC = nil
+		}
+	}
+	return nil
+}
+
+// annotationMarkup returns the markup fields of the common markup
+// annotations, or nil.
+func annotationMarkup(a *model.PdfAnnotation) *model.PdfAnnotationMarkup {
+	switch ctx := a.GetContext().(type) {
+	case *model.PdfAnnotationText:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationFreeText:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationHighlight:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationUnderline:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationSquiggly:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationStrikeOut:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationSquare:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationCircle:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationStamp:
+		return ctx.PdfAnnotationMarkup
+	case *model.PdfAnnotationInk:
+		return ctx.PdfAnnotationMarkup
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func upper(s string) (string, error) { return strings.ToUpper(s), nil }
+
+func TestTranslateMetadata(t *testing.T) {
+	entry := func(title string, page int64, entries ...*outlineEntry) *outlineEntry {
+		dest := core.MakeArray(core.MakeInteger(page), core.MakeName("Fit"))
+		return &outlineEntry{item: model.NewPdfOutlineItem(), title: title, page: page, dest: dest, entries: entries}
+	}
+	web := &outlineEntry{item: model.NewPdfOutlineItem(), title: "Site", page: -1}
+	web.item.A = core.MakeDict()
+	web.item.A.(*core.PdfObjectDictionary).Set("S", core.MakeName("URI"))
+	outline := []*outlineEntry{entry("Intro", 0), entry("Part", 1, entry("Detail", 2)), web}
+
+	info := &model.PdfInfo{
+		Title:  core.MakeString("Report"),
+		Author: core.MakeString("Someone"),
+	}
+	xmp := []byte(`<x:xmpmeta><dc:title><rdf:Alt><rdf:li xml:lang="x-default">a &amp; b</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:creator><rdf:Seq><rdf:li>Someone</rdf:li></rdf:Seq></dc:creator></x:xmpmeta>`)
+	m := docMetadata{outline: outline, info: info, xmp: xmp}
+
+	if got := strings.Join(m.texts(), "|"); got != "Intro|Part|Detail|Site|Report|a & b" {
+		t.Fatalf("texts=%q", got)
+	}
+
+	// Page 2 (index 1) is left out; pages 1 and 3 come out as the second
+	// and fourth output pages.
+	if err := m.translate(upper, map[int64]int64{0: 1, 2: 3}); err != nil {
+		t.Fatal(err)
+	}
+	items := m.outline
+	if len(items) != 3 || items[0].title != "INTRO" || items[1].title != "DETAIL" || items[2].title != "SITE" {
+		t.Fatalf("outline=%+v %+v", items[0], items[1])
+	}
+	for i, want := range []int64{1, 3} {
+		if page, _ := core.GetIntVal(items[i].dest.Get(0)); int64(page) != want {
+			t.Fatalf("item %d goes to %v", i, items[i].dest)
+		}
+	}
+	// The web link goes nowhere in the document and keeps its action.
+	tree := outlineTree(items)
+	last := tree.Last.GetContext().(*model.PdfOutlineItem)
+	if last.Dest != nil || last.A != web.item.A || *tree.Count != 3 {
+		t.Fatalf("web link=%+v", last)
+	}
+	if m.info.Title.Decoded() != "REPORT" || m.info.Author.Decoded() != "Someone" {
+		t.Fatalf("info=%+v", m.info)
+	}
+	want := `<x:xmpmeta><dc:title><rdf:Alt><rdf:li xml:lang="x-default">A &amp; B</rdf:li></rdf:Alt></dc:title>` +
+		`<dc:creator><rdf:Seq><rdf:li>Someone</rdf:li></rdf:Seq></dc:creator></x:xmpmeta>`
+	if got := string(m.xmp); got != want {
+		t.Fatalf("xmp=%s", got)
+	}
+}
+
+func TestTranslateAnnotations(t *testing.T) {
+	note := model.NewPdfAnnotationText()
+	note.Contents = core.MakeString("Check this")
+	note.RC = core.MakeString("<body>Check this</body>")
+	link := model.NewPdfAnnotationLink()
+	link.Contents = core.MakeString("Go to the appendix")
+	empty := model.NewPdfAnnotationText()
+
+	page := model.NewPdfPage()
+	page.SetAnnotations([]*model.PdfAnnotation{note.PdfAnnotation, link.PdfAnnotation, empty.PdfAnnotation})
+	annots := pageAnnotations(page)
+	if len(annots) != 2 {
+		t.Fatalf("annotations=%d", len(annots))
+	}
+	if err := translateAnnotations(annots, upper); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := core.GetString(note.Contents); s.Decoded() != "CHECK THIS" || note.RC != nil {
+		t.Fatalf("note=%v rc=%v", note.Contents, note.RC)
+	}
+	if s, _ := core.GetString(link.Contents); s.Decoded() != "GO TO THE APPENDIX" {
+		t.Fatalf("link=%v", link.Contents)
+	}
+
+	doc := &Document{pages: []extractedPage{{annotations: annots}}, opts: newOptions(nil)}
+	if got := doc.CountChunks(0); got != 2 {
+		t.Fatalf("chunks=%d", got)
+	}
+
+	// A title repeated in the metadata is translated, and counted, once.
+	doc.meta = docMetadata{
+		outline: []*outlineEntry{{item: model.NewPdfOutlineItem(), title: "CHECK THIS", page: -1}},
+		info:    &model.PdfInfo{Title: core.MakeString("Report"), Subject: core.MakeString("Report")},
+	}
+	if got := doc.CountChunks(0); got != 3 {
+		t.Fatalf("chunks with metadata=%d", got)
+	}
+}
+
+func TestOutlineDest(t *testing.T) {
+	page := core.MakeIndirectObject(core.MakeDict())
+	explicit := core.MakeArray(page, core.MakeName("Fit"))
+	names := func(name core.PdfObject) core.PdfObject {
+		if s, _ := core.GetStringVal(name); s == "intro" {
+			return core.MakeDict()
+		}
+		if n, _ := core.GetNameVal(name); n == "intro" {
+			d := core.MakeDict()
+			d.Set("D", explicit)
+			return d
+		}
+		return nil
+	}
+	goTo := func(d core.PdfObject) core.PdfObject {
+		a := core.MakeDict()
+		a.Set("S", core.MakeName("GoTo"))
+		a.Set("D", d)
+		return a
+	}
+	uri := core.MakeDict()
+	uri.Set("S", core.MakeName("URI"))
+
+	cases := []struct {
+		dest, action core.PdfObject
+		want         bool
+	}{
+		{dest: explicit, want: true},
+		{action: goTo(explicit), want: true},
+		{dest: core.MakeName("intro"), want: true},
+		{action: goTo(core.MakeString("intro"))},
+		{dest: core.MakeName("missing")},
+		{action: uri},
+		{},
+	}
+	for i, c := range cases {
+		item := model.NewPdfOutlineItem()
+		item.Dest, item.A = c.dest, c.action
+		dest, ok := outlineDest(item, names)
+		if ok != c.want || ok && dest != explicit {
+			t.Fatalf("case %d: dest=%v ok=%v", i, dest, ok)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package pdf
+
+import (
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// WithVerticalText draws translations of vertically written lines, such as
+// Japanese tategaki, as upright characters in columns running top to bottom
+// and right to left. It suits CJK targets; without it such lines are drawn
+// as text turned to run down the column.
+func WithVerticalText(enabled bool) Option {
+	return func(o *options) {
+		o.verticalText = enabled
+	}
+}
+
+// normalizeAngle returns angle in degrees as one of 0, 90, 180 or 270.
+func normalizeAngle(angle int) int {
+	angle = (angle%360 + 360) % 360
+	return (angle + 45) / 90 * 90 % 360
+}
+
+// rotateBox turns box by angle degrees clockwise about the origin. Turning a
+// line's box by its direction gives its box in reading space, where the
+// text runs left to right and lines stack downwards.
+func rotateBox(box model.PdfRectangle, angle int) model.PdfRectangle {
+	switch normalizeAngle(angle) {
+	case 90:
+		return model.PdfRectangle{Llx: box.Lly, Lly: -box.Urx, Urx: box.Ury, Ury: -box.Llx}
+	case 180:
+		return model.PdfRectangle{Llx: -box.Urx, Lly: -box.Ury, Urx: -box.Llx, Ury: -box.Lly}
+	case 270:
+		return model.PdfRectangle{Llx: -box.Ury, Lly: box.Llx, Urx: -box.Lly, Ury: box.Urx}
+	}
+	return box
+}
+
+// unrotatePoint turns the point (x, y) by angle degrees anticlockwise about
+// the origin, undoing rotateBox.
+func unrotatePoint(x, y float64, angle int) (float64, float64) {
+	switch normalizeAngle(angle) {
+	case 90:
+		return -y, x
+	case 180:
+		return -x, -y
+	case 270:
+		return y, -x
+	}
+	return x, y
+}
+
+// pageSize returns the width and height of a page with mediaBox as it is
+// displayed, turned clockwise by rotate degrees.
+func pageSize(mediaBox model.PdfRectangle, rotate int) (float64, float64) {
+	if normalizeAngle(rotate)%180 == 90 {
+		return mediaBox.Height(), mediaBox.Width()
+	}
+	return mediaBox.Width(), mediaBox.Height()
+}
+
+// toDisplay maps box from the user space of a page to the page as
+// displayed: turned clockwise by rotate degrees, with the origin at its
+// bottom left corner.
+func toDisplay(box, mediaBox model.PdfRectangle, rotate int) model.PdfRectangle {
+	box = model.PdfRectangle{
+		Llx: box.Llx - mediaBox.Llx, Lly: box.Lly - mediaBox.Lly,
+		Urx: box.Urx - mediaBox.Llx, Ury: box.Ury - mediaBox.Lly,
+	}
+	w, h := mediaBox.Width(), mediaBox.Height()
+	box = rotateBox(box, rotate)
+	var dx, dy float64
+	switch normalizeAngle(rotate) {
+	case 90:
+		dy = w
+	case 180:
+		dx, dy = w, h
+	case 270:
+		dx = h
+	}
+	return model.PdfRectangle{Llx: box.Llx + dx, Lly: box.Lly + dy, Urx: box.Urx + dx, Ury: box.Ury + dy}
+}
+
+// displayMarks maps the marks of a page to its displayed orientation, so
+// that line building and reading order work on the page as it is read. The
+// orientation of each mark becomes the direction its text runs on the
+// displayed page, in degrees anticlockwise.
+func displayMarks(marks []extractor.TextMark, mediaBox model.PdfRectangle, rotate int) []extractor.TextMark {
+	out := make([]extractor.TextMark, len(marks))
+	for i, m := range marks {
+		if !m.Meta {
+			m.BBox = toDisplay(m.BBox, mediaBox, rotate)
+			m.Orientation = normalizeAngle(m.Orientation - rotate)
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// readingBox is the box of line in its reading space.
+func readingBox(line textLine) model.PdfRectangle {
+	return rotateBox(line.Box, line.Dir)
+}
+
+// verticalMeasure measures upright text in a vertical column: every
+// character takes one em.
+func verticalMeasure(s string) float64 {
+	return float64(utf8.RuneCountInString(s))
+}
+
+// surface is a draw target together with how it maps displayed page
+// coordinates, with the origin at the bottom left, to its own.
+type surface struct {
+	target drawTarget
+	// width and height are the size of the target's coordinate space.
+	width, height float64
+	// rotate is the clockwise rotation the target applies when it is
+	// drawn: the page's rotation for a block holding an imported page, 0
+	// for a creator page, whose coordinates are already those of the
+	// displayed page.
+	rotate int
+}
+
+// point maps the displayed page point (x, y) to the target's coordinates,
+// measured from its top left corner.
+func (s surface) point(x, y float64) (float64, float64) {
+	switch normalizeAngle(s.rotate) {
+	case 90:
+		x, y = s.width-y, x
+	case 180:
+		x, y = s.width-x, s.height-y
+	case 270:
+		x, y = y, s.height-x
+	}
+	return x, s.height - y
+}
+
+// rect maps a displayed page box to the target's coordinates, returning its
+// top left corner and its size.
+func (s surface) rect(box model.PdfRectangle) (x, y, w, h float64) {
+	x0, y0 := s.point(box.Llx, box.Lly)
+	x1, y1 := s.point(box.Urx, box.Ury)
+	return minFloat(x0, x1), minFloat(y0, y1), maxFloat(x0, x1) - minFloat(x0, x1), maxFloat(y0, y1) - minFloat(y0, y1)
+}
+
+// angle maps the direction of displayed text, in degrees anticlockwise, to
+// the target.
+func (s surface) angle(dir int) float64 {
+	return float64(normalizeAngle(dir + s.rotate))
+}
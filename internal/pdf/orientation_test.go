@@ -0,0 +1,89 @@
+package pdf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestToDisplayRotatedPage(t *testing.T) {
+	media := model.PdfRectangle{Urx: 612, Ury: 792}
+	// Text near the top left of the unrotated page ends up at the top right
+	// of a page turned clockwise.
+	box := model.PdfRectangle{Llx: 72, Lly: 700, Urx: 100, Ury: 710}
+	got := toDisplay(box, media, 90)
+	want := model.PdfRectangle{Llx: 700, Lly: 512, Urx: 710, Ury: 540}
+	if got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if w, h := pageSize(media, 90); w != 792 || h != 612 {
+		t.Fatalf("size=%gx%g", w, h)
+	}
+	for _, rotate := range []int{0, 90, 180, 270, -90} {
+		s := surface{width: 612, height: 792, rotate: rotate}
+		// Mapping back to the page's own space from its top left corner
+		// undoes toDisplay.
+		d := toDisplay(box, media, rotate)
+		x, y, w, h := s.rect(d)
+		if x != 72 || y != 792-710 || w != 28 || h != 10 {
+			t.Fatalf("rotate %d: rect=%g,%g %gx%g", rotate, x, y, w, h)
+		}
+	}
+}
+
+func TestGroupLinesRotatedText(t *testing.T) {
+	// A header running upwards next to ordinary text.
+	marks := displayMarks([]extractor.TextMark{
+		{Text: "Up", BBox: model.PdfRectangle{Llx: 10, Lly: 100, Urx: 20, Ury: 120}, Orientation: 90},
+		{Text: "ward", BBox: model.PdfRectangle{Llx: 10, Lly: 121, Urx: 20, Ury: 160}, Orientation: 90},
+		{Text: "Across", BBox: model.PdfRectangle{Llx: 30, Lly: 100, Urx: 80, Ury: 110}},
+	}, model.PdfRectangle{Urx: 200, Ury: 200}, 0)
+	lines := groupLines(marks)
+	if len(lines) != 2 || lines[0].Text != "Upward" || lines[0].Dir != 90 || lines[1].Dir != 0 {
+		t.Fatalf("lines=%+v", lines)
+	}
+	box := readingBox(lines[0])
+	if box.Urx-box.Llx != 60 || box.Ury-box.Lly != 10 {
+		t.Fatalf("reading box=%+v", box)
+	}
+}
+
+func TestVerticalWriting(t *testing.T) {
+	// Two columns of vertical writing, one character per extracted line,
+	// read right to left.
+	var marks []extractor.TextMark
+	for col, text := range []string{"縦書", "次行"} {
+		x := 150 - float64(col)*15
+		for i, r := range []rune(text) {
+			y := 180 - float64(i)*10
+			marks = append(marks,
+				extractor.TextMark{Text: string(r), BBox: model.PdfRectangle{Llx: x, Lly: y - 10, Urx: x + 10, Ury: y}, FontSize: 10},
+				extractor.TextMark{Text: "\n", Meta: true})
+		}
+	}
+	blocks := pageBlocks(marks, model.PdfRectangle{Urx: 200, Ury: 200}, 0, nil)
+	if len(blocks) != 1 || blocks[0].Text != "縦書次行" {
+		t.Fatalf("blocks=%+v", blocks)
+	}
+	if l := blocks[0].Lines[0]; !l.Vertical || l.Dir != 270 {
+		t.Fatalf("line=%+v", l)
+	}
+
+	fit := fitBlock(blocks[0], "縦に書く", 0, 6, verticalMeasure)
+	if fit.overflow || fit.size != 10 || strings.Join(fit.lines, "|") != "縦に|書く" {
+		t.Fatalf("fit=%+v", fit)
+	}
+}
+
+func TestGroupLinesKeepsStackedLatinLines(t *testing.T) {
+	marks := []extractor.TextMark{
+		{Text: "1", BBox: model.PdfRectangle{Llx: 10, Lly: 100, Urx: 15, Ury: 110}},
+		{Text: "\n", Meta: true},
+		{Text: "2", BBox: model.PdfRectangle{Llx: 10, Lly: 89, Urx: 15, Ury: 99}},
+	}
+	if lines := groupLines(marks); len(lines) != 2 || lines[0].Vertical {
+		t.Fatalf("lines=%+v", lines)
+	}
+}
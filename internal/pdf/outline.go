@@ -0,0 +1,255 @@
+package pdf
+
+import (
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// outlineEntry is an outline item of the input. Items that go to a page of
+// the document have dest, their explicit destination, and page, the index
+// of the page from 0; the others, which open a web address or another file
+// or whose destination cannot be resolved, keep their action as it is.
+type outlineEntry struct {
+	item    *model.PdfOutlineItem
+	title   string
+	page    int64
+	dest    *core.PdfObjectArray
+	entries []*outlineEntry
+}
+
+// readOutline reads the outline of r as it is in the file. Unlike
+// r.GetOutlines, it keeps items without a page destination and those
+// following them.
+func readOutline(r *model.PdfReader) []*outlineEntry {
+	tree := r.GetOutlineTree()
+	if tree == nil {
+		return nil
+	}
+	names := namedDestinations(r)
+	seen := map[*model.PdfOutlineItem]bool{}
+	var read func(node *model.PdfOutlineTreeNode) []*outlineEntry
+	read = func(node *model.PdfOutlineTreeNode) []*outlineEntry {
+		var out []*outlineEntry
+		for n := node.First; n != nil; {
+			item, ok := n.GetContext().(*model.PdfOutlineItem)
+			if !ok || seen[item] {
+				break
+			}
+			seen[item] = true
+			e := &outlineEntry{item: item, page: -1, entries: read(&item.PdfOutlineTreeNode)}
+			if item.Title != nil {
+				e.title = item.Title.Decoded()
+			}
+			if dest, ok := outlineDest(item, names); ok {
+				if page, ok := pageIndex(r, dest); ok {
+					e.page, e.dest = page, dest
+				}
+			}
+			out = append(out, e)
+			n = item.Next
+		}
+		return out
+	}
+	return read(tree)
+}
+
+// outlineDest returns the explicit destination of item: its Dest or the
+// destination of its GoTo action, with named destinations looked up.
+func outlineDest(item *model.PdfOutlineItem, names func(core.PdfObject) core.PdfObject) (*core.PdfObjectArray, bool) {
+	dest := item.Dest
+	if dest == nil {
+		if !isGoTo(item.A) {
+			return nil, false
+		}
+		action, _ := core.GetDict(item.A)
+		dest = action.Get("D")
+	}
+	dest = core.ResolveReference(dest)
+	switch dest.(type) {
+	case *core.PdfObjectName, *core.PdfObjectString:
+		dest = core.ResolveReference(names(dest))
+	}
+	if d, ok := core.GetDict(dest); ok {
+		dest = d.Get("D")
+	}
+	arr, ok := core.GetArray(dest)
+	return arr, ok && arr.Len() > 0
+}
+
+func isGoTo(action core.PdfObject) bool {
+	d, ok := core.GetDict(action)
+	if !ok {
+		return false
+	}
+	s, _ := core.GetNameVal(d.Get("S"))
+	return s == "GoTo"
+}
+
+// namedDestinations returns a lookup of the named destinations of r: names
+// in the catalog's Dests dictionary and strings in the Dests name tree.
+func namedDestinations(r *model.PdfReader) func(core.PdfObject) core.PdfObject {
+	var dests, tree *core.PdfObjectDictionary
+	if obj, err := r.GetNamedDestinations(); err == nil {
+		dests, _ = core.GetDict(obj)
+	}
+	if obj, err := r.GetNameDictionary(); err == nil {
+		if names, ok := core.GetDict(obj); ok {
+			tree, _ = core.GetDict(names.Get("Dests"))
+		}
+	}
+	return func(name core.PdfObject) core.PdfObject {
+		if n, ok := core.GetNameVal(name); ok && dests != nil {
+			return dests.Get(core.PdfObjectName(n))
+		}
+		if s, ok := core.GetStringVal(name); ok && tree != nil {
+			return nameTreeLookup(tree, s, map[*core.PdfObjectDictionary]bool{})
+		}
+		return nil
+	}
+}
+
+// nameTreeLookup returns the value of key in the name tree node, or nil.
+func nameTreeLookup(node *core.PdfObjectDictionary, key string, seen map[*core.PdfObjectDictionary]bool) core.PdfObject {
+	if seen[node] {
+		return nil
+	}
+	seen[node] = true
+	if names, ok := core.GetArray(node.Get("Names")); ok {
+		for i := 0; i+1 < names.Len(); i += 2 {
+			if k, ok := core.GetStringVal(names.Get(i)); ok && k == key {
+				return names.Get(i + 1)
+			}
+		}
+	}
+	if kids, ok := core.GetArray(node.Get("Kids")); ok {
+		for _, kid := range kids.Elements() {
+			if d, ok := core.GetDict(kid); ok {
+				if v := nameTreeLookup(d, key, seen); v != nil {
+					return v
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// pageIndex returns the index, from 0, of the page dest goes to.
+func pageIndex(r *model.PdfReader, dest *core.PdfObjectArray) (int64, bool) {
+	var ind *core.PdfIndirectObject
+	switch obj := dest.Get(0).(type) {
+	case *core.PdfIndirectObject:
+		ind = obj
+	case *core.PdfObjectReference:
+		resolved, err := r.GetIndirectObjectByNumber(int(obj.ObjectNumber))
+		if err != nil {
+			return -1, false
+		}
+		ind, _ = resolved.(*core.PdfIndirectObject)
+	case *core.PdfObjectInteger:
+		return int64(*obj), true
+	}
+	if ind == nil {
+		return -1, false
+	}
+	_, num, err := r.PageFromIndirectObject(ind)
+	if err != nil {
+		return -1, false
+	}
+	return int64(num - 1), true
+}
+
+func outlineTitles(entries []*outlineEntry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.title)
+		out = append(out, outlineTitles(e.entries)...)
+	}
+	return out
+}
+
+func translateOutline(entries []*outlineEntry, translate func(string) (string, error)) error {
+	for _, e := range entries {
+		if strings.TrimSpace(e.title) != "" {
+			title, err := translate(e.title)
+			if err != nil {
+				return err
+			}
+			e.title = title
+		}
+		if err := translateOutline(e.entries, translate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func remapOutline(entries []*outlineEntry, pages map[int64]int64) []*outlineEntry {
+	var out []*outlineEntry
+	for _, e := range entries {
+		e.entries = remapOutline(e.entries, pages)
+		if e.dest == nil {
+			out = append(out, e)
+			continue
+		}
+		page, ok := pages[e.page]
+		if !ok {
+			out = append(out, e.entries...)
+			continue
+		}
+		elements := append([]core.PdfObject{core.MakeInteger(page)}, e.dest.Elements()[1:]...)
+		e.dest = core.MakeArray(elements...)
+		out = append(out, e)
+	}
+	return out
+}
+
+// outlineTree builds the output outline from entries. Items that go to a
+// page get their new destination and the others keep their action, but not
+// a GoTo action whose destination could not be resolved, which would point
+// into the input document.
+func outlineTree(entries []*outlineEntry) *model.PdfOutline {
+	root := model.NewPdfOutline()
+	if shown := linkOutline(&root.PdfOutlineTreeNode, entries); shown > 0 {
+		root.Count = &shown
+	}
+	return root
+}
+
+// linkOutline adds items for entries under parent and returns how many of
+// them are shown when parent is open. Closed items stay closed.
+func linkOutline(parent *model.PdfOutlineTreeNode, entries []*outlineEntry) int64 {
+	var shown int64
+	var prev *model.PdfOutlineItem
+	for _, e := range entries {
+		item := model.NewPdfOutlineItem()
+		item.Title = core.MakeEncodedString(e.title, true)
+		item.C, item.F = e.item.C, e.item.F
+		if e.dest != nil {
+			item.Dest = e.dest
+		} else if e.item.Dest == nil && !isGoTo(e.item.A) {
+			item.A = e.item.A
+		}
+		item.Parent = parent
+		if prev == nil {
+			parent.First = &item.PdfOutlineTreeNode
+		} else {
+			prev.Next = &item.PdfOutlineTreeNode
+			item.Prev = &prev.PdfOutlineTreeNode
+		}
+		parent.Last = &item.PdfOutlineTreeNode
+		prev = item
+
+		shown++
+		if n := linkOutline(&item.PdfOutlineTreeNode, e.entries); n > 0 {
+			if e.item.Count != nil && *e.item.Count < 0 {
+				n = -n
+			} else {
+				shown += n
+			}
+			item.Count = &n
+		}
+	}
+	return shown
+}
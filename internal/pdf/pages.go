@@ -0,0 +1,100 @@
+package pdf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageRanges selects pages by number. A nil PageRanges selects every page.
+type PageRanges []pageRange
+
+// pageRange is an inclusive range of page numbers. A zero stop runs to the
+// last page.
+type pageRange struct {
+	start int
+	stop  int
+}
+
+// ParsePages parses a page selection such as "1-3,10,20-". An empty spec
+// selects every page.
+func ParsePages(spec string) (PageRanges, error) {
+	var ranges PageRanges
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := parsePageNumber(first, part)
+		if err != nil {
+			return nil, err
+		}
+		stop := start
+		if isRange {
+			stop = 0
+			if strings.TrimSpace(last) != "" {
+				if stop, err = parsePageNumber(last, part); err != nil {
+					return nil, err
+				}
+				if stop < start {
+					return nil, fmt.Errorf("invalid page range %q", part)
+				}
+			}
+		}
+		ranges = append(ranges, pageRange{start: start, stop: stop})
+	}
+	return ranges, nil
+}
+
+func parsePageNumber(s, part string) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid page range %q", part)
+	}
+	return n, nil
+}
+
+// Contains reports whether page n is selected.
+func (r PageRanges) Contains(n int) bool {
+	if r == nil {
+		return true
+	}
+	for _, pr := range r {
+		if n >= pr.start && (pr.stop == 0 || n <= pr.stop) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedPages returns the selected page numbers of a document with count
+// pages, or an error when the selection is empty.
+func selectedPages(r PageRanges, count int) ([]int, error) {
+	var pages []int
+	for n := 1; n <= count; n++ {
+		if r.Contains(n) {
+			pages = append(pages, n)
+		}
+	}
+	if len(pages) == 0 && count > 0 {
+		return nil, fmt.Errorf("no pages selected (document has %d pages)", count)
+	}
+	return pages, nil
+}
+
+// WithPages restricts translation, counting and extraction to the selected
+// pages.
+func WithPages(pages PageRanges) Option {
+	return func(o *options) {
+		o.pages = pages
+	}
+}
+
+// WithSelectedPagesOnly leaves pages that are not selected out of the
+// translated document instead of copying them unchanged.
+func WithSelectedPagesOnly(only bool) Option {
+	return func(o *options) {
+		o.selectedOnly = only
+	}
+}
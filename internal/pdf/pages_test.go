@@ -0,0 +1,43 @@
+package pdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePages(t *testing.T) {
+	r, err := ParsePages("1-3, 10,20-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := selectedPages(r, 22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{1, 2, 3, 10, 20, 21, 22}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("pages=%v", got)
+	}
+}
+
+func TestParsePagesEmptySelectsAll(t *testing.T) {
+	r, err := ParsePages("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Contains(1) || !r.Contains(400) {
+		t.Fatalf("ranges=%v", r)
+	}
+}
+
+func TestParsePagesErrors(t *testing.T) {
+	for _, spec := range []string{"0", "a-b", "5-2", "-3", "1,,x"} {
+		if _, err := ParsePages(spec); err == nil {
+			t.Fatalf("ParsePages(%q) succeeded", spec)
+		}
+	}
+	r, _ := ParsePages("20-")
+	if _, err := selectedPages(r, 10); err == nil {
+		t.Fatal("selection beyond the last page succeeded")
+	}
+}
@@ -0,0 +1,133 @@
+package pdf
+
+import (
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// textBlock is a paragraph made of consecutive lines that share a left
+// margin, font size and line spacing. Text is the joined, de-hyphenated
+// paragraph text. Cell is the table cell of the block, or the zero box.
+type textBlock struct {
+	Lines []textLine
+	Text  string
+	Box   model.PdfRectangle
+	Cell  model.PdfRectangle
+}
+
+// groupParagraphs merges lines into paragraphs. A line joins the previous
+// paragraph when it starts at the same left margin (the paragraph's first
+// line may be indented), has a similar font size and weight, and sits one
+// line below, at the same spacing as the lines before it. Rotated text and
+// vertical columns are measured along their own reading direction. The
+// lines of a table cell always make one paragraph of their own.
+func groupParagraphs(lines []textLine) []textBlock {
+	var blocks []textBlock
+	for _, line := range lines {
+		if n := len(blocks); n > 0 && continuesParagraph(blocks[n-1], line) {
+			b := &blocks[n-1]
+			b.Text = joinLine(b.Text, line.Text)
+			b.Box = unionBox(b.Box, line.Box)
+			b.Lines = append(b.Lines, line)
+			continue
+		}
+		blocks = append(blocks, textBlock{Lines: []textLine{line}, Text: strings.TrimSpace(line.Text), Box: line.Box, Cell: line.Cell})
+	}
+	return blocks
+}
+
+func continuesParagraph(b textBlock, line textLine) bool {
+	last := b.Lines[len(b.Lines)-1]
+	if line.Cell != last.Cell {
+		return false
+	}
+	if line.Cell != (model.PdfRectangle{}) {
+		return true
+	}
+	size := lineSize(last)
+	if size <= 0 || math.Abs(lineSize(line)-size) > 0.15*size {
+		return false
+	}
+	// A bold line next to regular ones is a heading.
+	if line.Bold != last.Bold {
+		return false
+	}
+	// Rotated lines and vertical columns are compared in reading space.
+	if line.Dir != last.Dir || line.Vertical != last.Vertical {
+		return false
+	}
+	lastBox, box := readingBox(last), readingBox(line)
+
+	// Left margin: equal to the previous line, or to the right of it for an
+	// indented first line.
+	margin := box.Llx - lastBox.Llx
+	tolerance := math.Max(2, 0.3*size)
+	switch {
+	case math.Abs(margin) <= tolerance:
+	case len(b.Lines) == 1 && margin < 0 && -margin <= 4*size:
+	default:
+		return false
+	}
+
+	// Baseline distance: one line down, and consistent within the paragraph.
+	spacing := lastBox.Lly - box.Lly
+	if spacing <= 0 || spacing > 1.8*size {
+		return false
+	}
+	if len(b.Lines) >= 2 {
+		prev := b.Lines[len(b.Lines)-2]
+		if expected := readingBox(prev).Lly - lastBox.Lly; math.Abs(spacing-expected) > 0.2*expected {
+			return false
+		}
+	}
+	return true
+}
+
+// lineSize is the font size of a line, falling back to its box height.
+func lineSize(line textLine) float64 {
+	if line.FontSize > 0 {
+		return line.FontSize
+	}
+	box := readingBox(line)
+	return box.Ury - box.Lly
+}
+
+// joinLine appends a wrapped line to paragraph text. A hyphen that splits a
+// word at the line end is removed, and CJK lines are joined without a space.
+func joinLine(text, next string) string {
+	next = strings.TrimSpace(next)
+	text = strings.TrimRight(text, " ")
+	if text == "" {
+		return next
+	}
+	if next == "" {
+		return text
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	first, _ := utf8.DecodeRuneInString(next)
+	if last == '-' || last == '­' {
+		stem := text[:len(text)-utf8.RuneLen(last)]
+		before, _ := utf8.DecodeLastRuneInString(stem)
+		word := stem[strings.LastIndexAny(stem, " \t")+1:]
+		// Compounds that already contain a hyphen keep it.
+		if unicode.IsLetter(before) && unicode.IsLower(first) && !strings.Contains(word, "-") {
+			return stem + next
+		}
+		if last == '-' {
+			return text + next
+		}
+	}
+	if isCJK(last) && isCJK(first) {
+		return text + next
+	}
+	return text + " " + next
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303f) || (r >= 0xff00 && r <= 0xffef)
+}
@@ -0,0 +1,57 @@
+package pdf
+
+import (
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func line(text string, llx, lly, urx float64) textLine {
+	return textLine{Text: text, Box: model.PdfRectangle{Llx: llx, Lly: lly, Urx: urx, Ury: lly + 10}, FontSize: 10}
+}
+
+func TestGroupParagraphs(t *testing.T) {
+	lines := []textLine{
+		line("Heading", 72, 700, 150),
+		line("  The quick brown fox jumps over the", 90, 670, 400),
+		line("lazy dog and keeps run-", 72, 658, 400),
+		line("ning until the end.", 72, 646, 300),
+		line("A second paragraph.", 72, 620, 300),
+		line("Footnote in a smaller size.", 72, 608, 300),
+	}
+	lines[5].FontSize = 6
+
+	blocks := groupParagraphs(lines)
+	want := []string{
+		"Heading",
+		"The quick brown fox jumps over the lazy dog and keeps running until the end.",
+		"A second paragraph.",
+		"Footnote in a smaller size.",
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("blocks=%d: %+v", len(blocks), blocks)
+	}
+	for i, b := range blocks {
+		if b.Text != want[i] {
+			t.Fatalf("block %d text=%q", i, b.Text)
+		}
+	}
+	if box := blocks[1].Box; box.Llx != 72 || box.Lly != 646 || box.Urx != 400 || box.Ury != 680 {
+		t.Fatalf("block 1 box=%+v", box)
+	}
+}
+
+func TestJoinLine(t *testing.T) {
+	cases := []struct{ text, next, want string }{
+		{"trans-", "lation", "translation"},
+		{"well-", "Known", "well-Known"},
+		{"state-of-the-", "art", "state-of-the-art"},
+		{"end.", "Next", "end. Next"},
+		{"日本語の", "文章です。", "日本語の文章です。"},
+	}
+	for _, c := range cases {
+		if got := joinLine(c.text, c.next); got != c.want {
+			t.Fatalf("joinLine(%q, %q)=%q, want %q", c.text, c.next, got, c.want)
+		}
+	}
+}
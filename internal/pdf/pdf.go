@@ -2,202 +2,147 @@ package pdf
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"github.com/fuba/translate/internal/chunk"
 	"github.com/fuba/translate/internal/translate"
 	"github.com/unidoc/unipdf/v4/common/license"
 	"github.com/unidoc/unipdf/v4/creator"
-	"github.com/unidoc/unipdf/v4/extractor"
 	"github.com/unidoc/unipdf/v4/model"
 )
 
-func Translate(ctx context.Context, tr translate.Translator, inPath, outPath, from, to, unidocKey string, maxChars int, progress func(string), fontPath string) error {
-	if strings.TrimSpace(unidocKey) == "" {
-		return errors.New("unidoc key is required for PDF translation")
-	}
-	if err := setLicense(unidocKey); err != nil {
-		return fmt.Errorf("set unidoc license: %w", err)
-	}
-
-	f, err := os.Open(inPath)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-
-	pdfReader, err := model.NewPdfReader(f)
-	if err != nil {
-		return err
-	}
-
-	encrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return err
-	}
-	if encrypted {
-		ok, err := pdfReader.Decrypt([]byte(""))
-		if err != nil {
-			return err
-		}
-		if !ok {
-			return errors.New("encrypted pdf requires password")
-		}
-	}
+// Page layouts for WithLayout.
+const (
+	// LayoutOverlay covers each original line with its translation.
+	LayoutOverlay = "overlay"
+	// LayoutSideBySide places each original page to the left of its
+	// translated copy on a double-width page.
+	LayoutSideBySide = "side-by-side"
+	// LayoutInterleavePages follows each original page with its translated
+	// copy.
+	LayoutInterleavePages = "interleave-pages"
+)
 
-	count, err := pdfReader.GetNumPages()
-	if err != nil {
-		return err
-	}
+type Option func(*options)
+
+type options struct {
+	layout         string
+	minFontSize    float64
+	report         io.Writer
+	password       string
+	prompt         func() (string, error)
+	keepEncryption bool
+	pages          PageRanges
+	selectedOnly   bool
+	redaction      string
+	boldFont       string
+	italicFont     string
+	boldItalicFont string
+	fallbackFonts  []string
+	verticalText   bool
+}
 
-	c := creator.New()
-	overlayFont, err := loadOverlayFont(fontPath)
-	if err != nil {
-		return err
+// WithLayout selects how translated pages are laid out. The default is
+// LayoutOverlay.
+func WithLayout(layout string) Option {
+	return func(o *options) {
+		o.layout = layout
 	}
+}
 
-	for pageNum := 1; pageNum <= count; pageNum++ {
-		page, err := pdfReader.GetPage(pageNum)
-		if err != nil {
-			return err
-		}
-		if err := c.AddPage(page); err != nil {
-			return err
-		}
-		if progress != nil {
-			progress(fmt.Sprintf("[page %d] translating", pageNum))
-		}
-		if err := overlayTranslatedLines(ctx, tr, c, page, from, to, maxChars, progress, overlayFont); err != nil {
-			return fmt.Errorf("page %d: %w", pageNum, err)
+// WithMinFontSize sets the smallest font size translations are shrunk to
+// when they do not fit the original text box. The default is
+// DefaultMinFontSize.
+func WithMinFontSize(size float64) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.minFontSize = size
 		}
 	}
-
-	return c.WriteToFile(outPath)
 }
 
-func ExtractText(inPath, unidocKey string) (string, error) {
-	if strings.TrimSpace(unidocKey) == "" {
-		return "", errors.New("unidoc key is required for PDF extraction")
-	}
-	if err := setLicense(unidocKey); err != nil {
-		return "", fmt.Errorf("set unidoc license: %w", err)
+// WithReport writes a line to w for each translation that could not be
+// drawn as intended: text that does not fit its box even at the minimum
+// font size, or characters no overlay font covers.
+func WithReport(w io.Writer) Option {
+	return func(o *options) {
+		o.report = w
 	}
+}
 
-	f, err := os.Open(inPath)
-	if err != nil {
-		return "", err
+func newOptions(opts []Option) options {
+	o := options{layout: LayoutOverlay, minFontSize: DefaultMinFontSize, redaction: RedactCover}
+	for _, opt := range opts {
+		opt(&o)
 	}
-	defer f.Close()
+	return o
+}
 
-	pdfReader, err := model.NewPdfReader(f)
-	if err != nil {
-		return "", err
-	}
+// drawTarget is where overlays are drawn: the current creator page or a
+// block holding an imported page.
+type drawTarget interface {
+	Draw(d creator.Drawable) error
+}
 
-	encrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return "", err
-	}
-	if encrypted {
-		ok, err := pdfReader.Decrypt([]byte(""))
-		if err != nil {
-			return "", err
-		}
-		if !ok {
-			return "", errors.New("encrypted pdf requires password")
+// addTranslatedPage adds p and its translation to c in the layout of o.
+// It returns warnings about translations that could not be drawn as
+// intended.
+func addTranslatedPage(ctx context.Context, tr translate.Translator, c *creator.Creator, p extractedPage, o options, from, to string, maxChars int, progress func(string), fonts fontSet) ([]string, error) {
+	page := p.page
+	switch o.layout {
+	case LayoutInterleavePages:
+		if err := c.AddPage(page.Duplicate()); err != nil {
+			return nil, err
 		}
-	}
-
-	count, err := pdfReader.GetNumPages()
-	if err != nil {
-		return "", err
-	}
-
-	pages := make([]string, 0, count)
-	for pageNum := 1; pageNum <= count; pageNum++ {
-		page, err := pdfReader.GetPage(pageNum)
+	case LayoutSideBySide:
+		original, err := creator.NewBlockFromPage(page.Duplicate())
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		ex, err := extractor.New(page)
-		if err != nil {
-			return "", err
+		if o.redaction == RedactRemove {
+			if err := removeText(page); err != nil {
+				return nil, err
+			}
 		}
-		pageText, _, _, err := ex.ExtractPageText()
+		translated, err := creator.NewBlockFromPage(page)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
-		pages = append(pages, pageText.Text())
-	}
-
-	return joinPageText(pages), nil
-}
-
-func CountChunks(inPath, unidocKey string, maxChars int) (int, error) {
-	if strings.TrimSpace(unidocKey) == "" {
-		return 0, errors.New("unidoc key is required for PDF extraction")
-	}
-	if err := setLicense(unidocKey); err != nil {
-		return 0, fmt.Errorf("set unidoc license: %w", err)
-	}
-
-	f, err := os.Open(inPath)
-	if err != nil {
-		return 0, err
-	}
-	defer f.Close()
-
-	pdfReader, err := model.NewPdfReader(f)
-	if err != nil {
-		return 0, err
-	}
-
-	encrypted, err := pdfReader.IsEncrypted()
-	if err != nil {
-		return 0, err
-	}
-	if encrypted {
-		ok, err := pdfReader.Decrypt([]byte(""))
+		// A block keeps the page's own coordinates and applies its rotation
+		// when drawn.
+		s := surface{target: translated, width: p.mediaBox.Width(), height: p.mediaBox.Height(), rotate: int(p.rotate)}
+		warnings, err := overlayTranslatedLines(ctx, tr, c, s, p, o, from, to, maxChars, progress, fonts)
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
-		if !ok {
-			return 0, errors.New("encrypted pdf requires password")
+		_, err = addSpread(c, original, translated, p.width, p.height)
+		return warnings, err
+	}
+	if o.redaction == RedactRemove {
+		if err := removeText(page); err != nil {
+			return nil, err
 		}
 	}
-
-	count, err := pdfReader.GetNumPages()
-	if err != nil {
-		return 0, err
+	if err := c.AddPage(page); err != nil {
+		return nil, err
 	}
+	return overlayTranslatedLines(ctx, tr, c, surface{target: c, width: p.width, height: p.height}, p, o, from, to, maxChars, progress, fonts)
+}
 
-	total := 0
-	for pageNum := 1; pageNum <= count; pageNum++ {
-		page, err := pdfReader.GetPage(pageNum)
-		if err != nil {
-			return 0, err
-		}
-		extract, err := extractor.New(page)
-		if err != nil {
-			return 0, err
-		}
-		pageText, _, _, err := extract.ExtractPageText()
-		if err != nil {
-			return 0, err
-		}
-		lines := groupLines(pageText.Marks().Elements())
-		for _, line := range lines {
-			if strings.TrimSpace(line.Text) == "" {
-				continue
-			}
-			total += len(chunk.Split(line.Text, maxChars))
-		}
+// addSpread adds a page twice as wide as a page width by height, with
+// original on its left half and translated on its right half, and returns
+// it.
+func addSpread(c *creator.Creator, original, translated *creator.Block, width, height float64) (*model.PdfPage, error) {
+	c.SetPageSize(creator.PageSize{2 * width, height})
+	page := c.NewPage()
+	original.SetPos(0, 0)
+	if err := c.Draw(original); err != nil {
+		return nil, err
 	}
-
-	return total, nil
+	translated.SetPos(width, 0)
+	return page, c.Draw(translated)
 }
 
 func setLicense(key string) error {
@@ -217,69 +162,150 @@ func isLicenseAlreadySet(err error) bool {
 	return strings.Contains(strings.ToLower(err.Error()), "license key already set")
 }
 
-func overlayTranslatedLines(ctx context.Context, tr translate.Translator, c *creator.Creator, page *model.PdfPage, from, to string, maxChars int, progress func(string), font *model.PdfFont) error {
-	ex, err := extractor.New(page)
-	if err != nil {
-		return err
-	}
-	pageText, _, _, err := ex.ExtractPageText()
-	if err != nil {
-		return err
-	}
-	mediaBox, err := page.GetMediaBox()
-	if err != nil {
-		return err
-	}
-	if page.MediaBox == nil {
-		page.MediaBox = mediaBox
-	}
-	pageHeight := mediaBox.Ury
-
-	lines := groupLines(pageText.Marks().Elements())
-	for _, line := range lines {
-		if strings.TrimSpace(line.Text) == "" {
+func overlayTranslatedLines(ctx context.Context, tr translate.Translator, c *creator.Creator, s surface, p extractedPage, o options, from, to string, maxChars int, progress func(string), fonts fontSet) ([]string, error) {
+	var warnings []string
+	for i, block := range p.blocks {
+		if strings.TrimSpace(block.Text) == "" {
 			continue
 		}
-		translated, err := translateChunked(ctx, tr, line.Text, from, to, maxChars, progress)
+		translated, err := translateChunked(ctx, tr, block.Text, from, to, maxChars, progress)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		first := block.Lines[0]
+		font, fakeBold := fonts.pick(first.Bold, first.Italic)
+		upright := first.Vertical && o.verticalText
+		measure := fonts.measure(font)
+		if upright {
+			measure = verticalMeasure
+		}
+		room := 0.0
+		switch {
+		case block.Cell != (model.PdfRectangle{}) && first.Dir == 0 && !upright:
+			// A table cell's translation shrinks to fit the cell.
+			block.Box = cellArea(block)
+		case first.Dir == 0:
+			room = roomBelow(p.blocks, i, 0)
+		}
+		fit := fitBlock(block, translated, room, o.minFontSize, measure)
+		if fit.overflow {
+			warnings = append(warnings, fmt.Sprintf("translation does not fit its box at %gpt: %q", o.minFontSize, translated))
 		}
-		drawLineOverlay(c, line, translated, pageHeight, font)
+		if !upright {
+			box := rotateBox(block.Box, first.Dir)
+			visualLines(&fit, isRTL(translated), box.Urx-box.Llx, measure)
+		}
+		if _, missing := fonts.runs(strings.Join(fit.lines, " "), font); len(missing) > 0 {
+			warnings = append(warnings, fmt.Sprintf("no font covers %q in %q", string(missing), translated))
+		}
+		drawBlockOverlay(c, s, block, fit, o.redaction == RedactCover, p.fills, fonts, font, fakeBold, upright)
 	}
-	return nil
+	return warnings, nil
 }
 
-func drawLineOverlay(c *creator.Creator, line textLine, translated string, pageHeight float64, font *model.PdfFont) {
-	y := pageHeight - line.Box.Ury
-	height := line.Box.Ury - line.Box.Lly
-	width := line.Box.Urx - line.Box.Llx
-
-	rect := c.NewRectangle(line.Box.Llx, y, width, height)
-	rect.SetFillColor(creator.ColorWhite)
-	rect.SetBorderColor(creator.ColorWhite)
-	_ = c.Draw(rect)
+// fitBlock lays out a translation in the box of block, keeping the original
+// font size and line spacing when the text fits. Otherwise the font shrinks
+// down to minSize, first within the box and then within the free space
+// below it, down to room. Sizes are taken along the block's reading
+// direction.
+func fitBlock(block textBlock, translated string, room, minSize float64, measure func(string) float64) fitted {
+	first := readingBox(block.Lines[0])
+	size := first.Ury - first.Lly
+	spacing := 1.2
+	if len(block.Lines) > 1 && size > 0 {
+		spacing = (first.Lly - readingBox(block.Lines[1]).Lly) / size
+	}
+	box := rotateBox(block.Box, block.Lines[0].Dir)
+	width, height := box.Urx-box.Llx, box.Ury-box.Lly
+	fit := fitText(translated, width, height, size, minSize, spacing, measure)
+	if fit.overflow && room > height {
+		if wrapped := fitText(translated, width, room, size, minSize, spacing, measure); !wrapped.overflow {
+			fit = wrapped
+		}
+	}
+	fit.spacing = spacing
+	return fit
+}
 
-	p := c.NewStyledParagraph()
-	p.SetText(translated)
-	p.SetPos(line.Box.Llx, y)
-	p.SetFontSize(height)
-	if font != nil {
-		p.SetFont(font)
+// roomBelow returns the height from the top of blocks[i] down to the
+// nearest block below it that shares some of its horizontal extent, or to
+// bottom.
+func roomBelow(blocks []textBlock, i int, bottom float64) float64 {
+	box := blocks[i].Box
+	limit := bottom
+	for j, other := range blocks {
+		b := other.Box
+		if j == i || b.Ury > box.Lly || b.Urx <= box.Llx || b.Llx >= box.Urx {
+			continue
+		}
+		limit = maxFloat(limit, b.Ury)
 	}
-	_ = c.Draw(p)
+	return box.Ury - limit
 }
 
-func loadOverlayFont(fontPath string) (*model.PdfFont, error) {
-	if strings.TrimSpace(fontPath) == "" {
-		return model.NewStandard14Font("Helvetica")
+// drawBlockOverlay draws the fitted translation from the start of the
+// block's box, turned to the direction of its text, in the colour of its
+// first line and in font, switching to the fallback fonts for characters
+// font lacks, and outlined when fakeBold is set. Right-to-left lines are
+// indented to end at the right edge. With upright, each line is a column of
+// upright characters. With cover, the original lines are first painted over
+// in the colour of the fills behind them.
+func drawBlockOverlay(c *creator.Creator, s surface, block textBlock, fit fitted, cover bool, fills []filledRect, fonts fontSet, font *overlayFont, fakeBold, upright bool) {
+	if cover {
+		for _, line := range block.Lines {
+			color := backgroundAt(fills, line.Box)
+			rect := c.NewRectangle(s.rect(line.Box))
+			rect.SetFillColor(color)
+			rect.SetBorderColor(color)
+			_ = s.target.Draw(rect)
+		}
 	}
-	if _, err := os.Stat(fontPath); err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("pdf font not found: %s", fontPath)
+
+	color := textColor(block.Lines[0].Color)
+	dir := block.Lines[0].Dir
+	box := rotateBox(block.Box, dir)
+	for i, text := range fit.lines {
+		top := box.Ury - float64(i)*fit.size*fit.spacing
+		if !upright {
+			left := box.Llx
+			if i < len(fit.indent) {
+				left += fit.indent[i]
+			}
+			p := styledText(c, text, fit.size, color, fonts, font, fakeBold)
+			p.SetPos(s.point(unrotatePoint(left, top, dir)))
+			p.SetAngle(s.angle(dir))
+			_ = s.target.Draw(p)
+			continue
+		}
+		for j, r := range []rune(text) {
+			left := box.Llx + float64(j)*fit.size
+			cell := rotateBox(model.PdfRectangle{Llx: left, Lly: top - fit.size, Urx: left + fit.size, Ury: top}, -dir)
+			p := styledText(c, string(r), fit.size, color, fonts, font, fakeBold)
+			p.SetPos(s.point(cell.Llx, cell.Ury))
+			p.SetAngle(s.angle(0))
+			_ = s.target.Draw(p)
+		}
+	}
+}
+
+// styledText returns an unwrapped paragraph of text at size, in font and
+// the fallback fonts.
+func styledText(c *creator.Creator, text string, size float64, color creator.Color, fonts fontSet, font *overlayFont, fakeBold bool) *creator.StyledParagraph {
+	p := c.NewStyledParagraph()
+	runs, _ := fonts.runs(text, font)
+	for _, run := range runs {
+		chunk := p.Append(run.text)
+		chunk.Style.Font = run.font.pdf
+		chunk.Style.FontSize = size
+		chunk.Style.Color = color
+		if fakeBold {
+			chunk.Style.RenderingMode = creator.TextRenderingModeFillStroke
+			chunk.Style.OutlineColor = color
+			chunk.Style.OutlineSize = size * 0.03
 		}
-		return nil, err
 	}
-	return model.NewCompositePdfFontFromTTFFile(fontPath)
+	p.SetEnableWrap(false)
+	return p
 }
 
 func translateChunked(ctx context.Context, tr translate.Translator, text, from, to string, maxChars int, progress func(string)) (string, error) {
@@ -298,10 +324,10 @@ func translateChunked(ctx context.Context, tr translate.Translator, text, from,
 	return b.String(), nil
 }
 
-func joinPageText(pages []string) string {
+func joinPageText(numbers []int, pages []string) string {
 	var b strings.Builder
 	for i, text := range pages {
-		b.WriteString(fmt.Sprintf("=== Page %d ===\n", i+1))
+		b.WriteString(fmt.Sprintf("=== Page %d ===\n", numbers[i]))
 		b.WriteString(text)
 		b.WriteString("\n\n")
 	}
@@ -0,0 +1,100 @@
+package pdf
+
+import (
+	"image/color"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/core"
+	"github.com/unidoc/unipdf/v4/creator"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// styleCounter finds the style most of a line's glyphs are drawn in.
+type styleCounter struct {
+	runes  int
+	bold   int
+	italic int
+	colors map[color.RGBA]int
+}
+
+func (s *styleCounter) add(m glyphStyle, n int) {
+	s.runes += n
+	if m.bold {
+		s.bold += n
+	}
+	if m.italic {
+		s.italic += n
+	}
+	if m.color != nil {
+		if s.colors == nil {
+			s.colors = map[color.RGBA]int{}
+		}
+		s.colors[color.RGBAModel.Convert(m.color).(color.RGBA)] += n
+	}
+}
+
+// apply sets the dominant style on line.
+func (s *styleCounter) apply(line *textLine) {
+	line.Bold = s.bold*2 > s.runes
+	line.Italic = s.italic*2 > s.runes
+	var dominant color.RGBA
+	best := 0
+	for c, n := range s.colors {
+		if n > best || n == best && rgbaLess(c, dominant) {
+			dominant, best = c, n
+		}
+	}
+	if best > 0 {
+		line.Color = dominant
+	}
+}
+
+func rgbaLess(a, b color.RGBA) bool {
+	return a.R < b.R || a.R == b.R && (a.G < b.G || a.G == b.G && a.B < b.B)
+}
+
+// glyphStyle is the style of one text mark.
+type glyphStyle struct {
+	color  color.Color
+	bold   bool
+	italic bool
+}
+
+// fontStyle reports whether font is bold or italic, from its descriptor
+// and, failing that, its name.
+func fontStyle(font *model.PdfFont) (bold, italic bool) {
+	if font == nil {
+		return false, false
+	}
+	name := strings.ToLower(font.BaseFont())
+	if i := strings.IndexByte(name, '+'); i == 6 {
+		name = name[i+1:] // subset prefix
+	}
+	for _, w := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		bold = bold || strings.Contains(name, w)
+	}
+	italic = strings.Contains(name, "italic") || strings.Contains(name, "oblique")
+
+	if d := font.FontDescriptor(); d != nil {
+		if flags, ok := core.GetIntVal(d.Flags); ok {
+			italic = italic || flags&(1<<6) != 0
+			bold = bold || flags&(1<<18) != 0
+		}
+		if weight, err := core.GetNumberAsFloat(d.FontWeight); err == nil && weight >= 600 {
+			bold = true
+		}
+		if angle, err := core.GetNumberAsFloat(d.ItalicAngle); err == nil && angle != 0 {
+			italic = true
+		}
+	}
+	return bold, italic
+}
+
+// textColor converts an extracted colour for drawing, defaulting to black.
+func textColor(c color.Color) creator.Color {
+	if c == nil {
+		return creator.ColorBlack
+	}
+	r, g, b, _ := c.RGBA()
+	return creator.ColorRGBFromArithmetic(float64(r)/0xffff, float64(g)/0xffff, float64(b)/0xffff)
+}
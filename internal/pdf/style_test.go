@@ -0,0 +1,53 @@
+package pdf
+
+import (
+	"image/color"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+func TestFontStyle(t *testing.T) {
+	cases := []struct {
+		name         model.StdFontName
+		bold, italic bool
+	}{
+		{"Helvetica", false, false},
+		{"Helvetica-Bold", true, false},
+		{"Times-Italic", false, true},
+		{"Courier-BoldOblique", true, true},
+	}
+	for _, c := range cases {
+		font, err := model.NewStandard14Font(c.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bold, italic := fontStyle(font); bold != c.bold || italic != c.italic {
+			t.Fatalf("%s: bold=%v italic=%v", c.name, bold, italic)
+		}
+	}
+}
+
+func TestGroupLinesDominantStyle(t *testing.T) {
+	bold, _ := model.NewStandard14Font("Helvetica-Bold")
+	regular, _ := model.NewStandard14Font("Helvetica")
+	red := color.RGBA{R: 200, A: 255}
+	marks := []extractor.TextMark{
+		{Text: "Heading", Font: bold, FillColor: red, BBox: model.PdfRectangle{Llx: 10, Lly: 30, Urx: 60, Ury: 40}},
+		{Text: "\n", Meta: true},
+		{Text: "a ", Font: bold, FillColor: color.Black, BBox: model.PdfRectangle{Llx: 10, Lly: 10, Urx: 20, Ury: 20}},
+		{Text: "regular line", Font: regular, FillColor: color.Black, BBox: model.PdfRectangle{Llx: 20, Lly: 10, Urx: 80, Ury: 20}},
+	}
+
+	lines := groupLines(marks)
+	if len(lines) != 2 {
+		t.Fatalf("lines=%d", len(lines))
+	}
+	if !lines[0].Bold || lines[0].Color != red {
+		t.Fatalf("heading=%+v", lines[0])
+	}
+	if lines[1].Bold || lines[1].Color != (color.RGBA{A: 255}) {
+		t.Fatalf("body=%+v", lines[1])
+	}
+}
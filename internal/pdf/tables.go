@@ -0,0 +1,288 @@
+package pdf
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// table is a region of a page divided into cells by ruling lines, or by
+// horizontal rules and the empty gutters between text columns.
+type table struct {
+	box model.PdfRectangle
+	// cols are the x boundaries of the columns, left to right, and rows the
+	// y boundaries of the rows, top to bottom. Both include the edges.
+	cols []float64
+	rows []float64
+}
+
+const (
+	// ruleSnap is the distance within which rules are taken to be on the
+	// same line or to touch.
+	ruleSnap = 2.0
+	// maxRowHeight is the tallest band between two horizontal rules of a
+	// table without vertical rules, so that rules far apart, such as a
+	// page's header and footer rules, do not make a table.
+	maxRowHeight = 200.0
+)
+
+// cell returns the box of the cell containing the point (x, y), or false.
+func (t table) cell(x, y float64) (model.PdfRectangle, bool) {
+	if x < t.box.Llx || x > t.box.Urx || y < t.box.Lly || y > t.box.Ury {
+		return model.PdfRectangle{}, false
+	}
+	col := sort.SearchFloat64s(t.cols, x)
+	row := sort.Search(len(t.rows), func(i int) bool { return t.rows[i] <= y })
+	col, row = min(max(col, 1), len(t.cols)-1), min(max(row, 1), len(t.rows)-1)
+	return model.PdfRectangle{Llx: t.cols[col-1], Lly: t.rows[row], Urx: t.cols[col], Ury: t.rows[row-1]}, true
+}
+
+// findTables finds tables from the ruling lines of a page. Horizontal rules
+// of about the same extent, at least two with vertical rules crossing them
+// or four otherwise, bound a table and its rows. Vertical rules inside it
+// divide the columns; without them, the columns are the gaps that no text
+// in the region crosses.
+func findTables(rules []model.PdfRectangle, marks []extractor.TextMark) []table {
+	var horizontal, vertical []model.PdfRectangle
+	for _, r := range rules {
+		if r.Ury-r.Lly <= ruleWidth {
+			horizontal = append(horizontal, r)
+		} else {
+			vertical = append(vertical, r)
+		}
+	}
+	horizontal = mergeRules(horizontal, true)
+	vertical = mergeRules(vertical, false)
+
+	var tables []table
+	for _, group := range groupRules(horizontal) {
+		box := group[0]
+		for _, r := range group[1:] {
+			box = unionBox(box, r)
+		}
+		var rows []float64
+		for _, r := range group {
+			rows = appendBoundary(rows, (r.Lly+r.Ury)/2)
+		}
+		sort.Sort(sort.Reverse(sort.Float64Slice(rows)))
+
+		var cols []float64
+		for _, v := range vertical {
+			x := (v.Llx + v.Urx) / 2
+			overlap := math.Min(v.Ury, box.Ury) - math.Max(v.Lly, box.Lly)
+			if x >= box.Llx-ruleSnap && x <= box.Urx+ruleSnap && overlap >= 0.5*(v.Ury-v.Lly) {
+				cols = appendBoundary(cols, x)
+			}
+		}
+		if len(cols) < 2 {
+			// Rules above and below two-column text, or between its
+			// sections, have gutters between them too; rows need at least
+			// two inner rules.
+			if len(rows) < 4 || !closeRows(rows) {
+				continue
+			}
+			cols = textGutters(box, marks)
+		}
+		cols = appendBoundary(appendBoundary(cols, box.Llx), box.Urx)
+		sort.Float64s(cols)
+		if len(cols) < 3 {
+			continue
+		}
+		tables = append(tables, table{box: box, cols: cols, rows: rows})
+	}
+	return tables
+}
+
+// mergeRules joins rules that continue each other along one line, as grids
+// drawn cell by cell are.
+func mergeRules(rules []model.PdfRectangle, horizontal bool) []model.PdfRectangle {
+	across := func(r model.PdfRectangle) float64 { return r.Lly + r.Ury }
+	start := func(r model.PdfRectangle) float64 { return r.Llx }
+	end := func(r model.PdfRectangle) float64 { return r.Urx }
+	if !horizontal {
+		across = func(r model.PdfRectangle) float64 { return r.Llx + r.Urx }
+		start = func(r model.PdfRectangle) float64 { return r.Lly }
+		end = func(r model.PdfRectangle) float64 { return r.Ury }
+	}
+	sorted := append([]model.PdfRectangle(nil), rules...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if a, b := across(sorted[i]), across(sorted[j]); math.Abs(a-b) > 2*ruleSnap {
+			return a < b
+		}
+		return start(sorted[i]) < start(sorted[j])
+	})
+	var out []model.PdfRectangle
+	for _, r := range sorted {
+		if n := len(out); n > 0 && math.Abs(across(out[n-1])-across(r)) <= 2*ruleSnap && start(r) <= end(out[n-1])+ruleSnap {
+			out[n-1] = unionBox(out[n-1], r)
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// groupRules groups horizontal rules that start and end at about the same
+// x, top to bottom.
+func groupRules(horizontal []model.PdfRectangle) [][]model.PdfRectangle {
+	sorted := append([]model.PdfRectangle(nil), horizontal...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Ury > sorted[j].Ury })
+	var groups [][]model.PdfRectangle
+	used := make([]bool, len(sorted))
+	for i, r := range sorted {
+		if used[i] {
+			continue
+		}
+		group := []model.PdfRectangle{r}
+		for j := i + 1; j < len(sorted); j++ {
+			o := sorted[j]
+			if !used[j] && math.Abs(o.Llx-r.Llx) <= 3*ruleSnap && math.Abs(o.Urx-r.Urx) <= 3*ruleSnap {
+				group = append(group, o)
+				used[j] = true
+			}
+		}
+		if len(group) >= 2 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// appendBoundary adds x to the boundaries unless one is within ruleSnap.
+func appendBoundary(bounds []float64, x float64) []float64 {
+	for _, b := range bounds {
+		if math.Abs(b-x) <= ruleSnap {
+			return bounds
+		}
+	}
+	return append(bounds, x)
+}
+
+// closeRows reports whether no two neighbouring row boundaries are more
+// than maxRowHeight apart.
+func closeRows(rows []float64) bool {
+	for i := 1; i < len(rows); i++ {
+		if rows[i-1]-rows[i] > maxRowHeight {
+			return false
+		}
+	}
+	return true
+}
+
+// textGutters returns the middles of the vertical gaps in box that no
+// glyph crosses and that are wider than the text is tall, which separate
+// the columns of a table drawn without vertical rules.
+func textGutters(box model.PdfRectangle, marks []extractor.TextMark) []float64 {
+	var spans [][2]float64
+	var heights []float64
+	for _, m := range marks {
+		b := m.BBox
+		if m.Meta || strings.TrimSpace(m.Text) == "" {
+			continue
+		}
+		cx, cy := (b.Llx+b.Urx)/2, (b.Lly+b.Ury)/2
+		if cx < box.Llx || cx > box.Urx || cy < box.Lly || cy > box.Ury {
+			continue
+		}
+		spans = append(spans, [2]float64{b.Llx, b.Urx})
+		heights = append(heights, b.Ury-b.Lly)
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	sort.Float64s(heights)
+	minGap := heights[len(heights)/2]
+	sort.Slice(spans, func(i, j int) bool { return spans[i][0] < spans[j][0] })
+
+	var gutters []float64
+	end := spans[0][1]
+	for _, s := range spans[1:] {
+		if s[0]-end >= minGap {
+			gutters = append(gutters, (s[0]+end)/2)
+		}
+		end = math.Max(end, s[1])
+	}
+	return gutters
+}
+
+// splitCells inserts line breaks between consecutive marks that fall in
+// different table cells, so that no line crosses a cell boundary.
+func splitCells(marks []extractor.TextMark, tables []table) []extractor.TextMark {
+	if len(tables) == 0 {
+		return marks
+	}
+	out := make([]extractor.TextMark, 0, len(marks))
+	var last model.PdfRectangle
+	seen := false
+	for _, m := range marks {
+		if !m.Meta {
+			cell, _ := cellAt(tables, m.BBox)
+			if seen && cell != last {
+				out = append(out, extractor.TextMark{Text: "\n", Meta: true})
+			}
+			last, seen = cell, true
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// cellAt returns the box of the table cell containing the centre of box,
+// or the zero box.
+func cellAt(tables []table, box model.PdfRectangle) (model.PdfRectangle, bool) {
+	x, y := (box.Llx+box.Urx)/2, (box.Lly+box.Ury)/2
+	for _, t := range tables {
+		if cell, ok := t.cell(x, y); ok {
+			return cell, true
+		}
+	}
+	return model.PdfRectangle{}, false
+}
+
+// markCells records the table cell of each line and moves the lines of
+// each cell next to the first one, so that a cell becomes one block.
+func markCells(lines []textLine, tables []table) []textLine {
+	if len(tables) == 0 {
+		return lines
+	}
+	out := make([]textLine, 0, len(lines))
+	placed := make([]bool, len(lines))
+	for i := range lines {
+		lines[i].Cell, _ = cellAt(tables, lines[i].Box)
+	}
+	for i, line := range lines {
+		if placed[i] {
+			continue
+		}
+		out = append(out, line)
+		if line.Cell == (model.PdfRectangle{}) {
+			continue
+		}
+		for j := i + 1; j < len(lines); j++ {
+			if !placed[j] && lines[j].Cell == line.Cell {
+				out = append(out, lines[j])
+				placed[j] = true
+			}
+		}
+	}
+	return out
+}
+
+// cellPadding is the space kept between a translation and its cell's
+// rules.
+const cellPadding = 1.5
+
+// cellArea returns the box a translation of block may fill: the width of
+// its cell, from the top of the original text to the bottom of the cell.
+func cellArea(block textBlock) model.PdfRectangle {
+	cell := block.Cell
+	return model.PdfRectangle{
+		Llx: cell.Llx + cellPadding,
+		Lly: cell.Lly + cellPadding,
+		Urx: cell.Urx - cellPadding,
+		Ury: math.Min(block.Box.Ury, cell.Ury-cellPadding),
+	}
+}
@@ -0,0 +1,120 @@
+package pdf
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/unidoc/unipdf/v4/extractor"
+	"github.com/unidoc/unipdf/v4/model"
+)
+
+// word returns a mark for text at (x, y), each character 6pt wide and
+// 10pt high.
+func word(text string, x, y float64) extractor.TextMark {
+	w := 6 * float64(len(text))
+	return extractor.TextMark{Text: text, BBox: model.PdfRectangle{Llx: x, Lly: y, Urx: x + w, Ury: y + 10}, FontSize: 10}
+}
+
+func hrule(x0, x1, y float64) model.PdfRectangle {
+	return model.PdfRectangle{Llx: x0, Lly: y, Urx: x1, Ury: y}
+}
+
+func vrule(x, y0, y1 float64) model.PdfRectangle {
+	return model.PdfRectangle{Llx: x, Lly: y0, Urx: x, Ury: y1}
+}
+
+func TestPageGraphicsRules(t *testing.T) {
+	page := testPage(t, "0 0 0 RG 50 200 m 250 200 l S\n"+
+		"50 160 200 40 re S\n"+
+		"0 0 0 rg 149.5 160 1 40 re f\n"+
+		"1 1 1 rg 0 0 612 792 re f\n")
+	_, rules, err := pageGraphics(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The line, the four sides of the stroked rectangle and the thin fill;
+	// the page background is not a rule.
+	if len(rules) != 6 {
+		t.Fatalf("rules=%+v", rules)
+	}
+}
+
+func TestTableCellsAreBlocks(t *testing.T) {
+	// Right-aligned text in the first column comes close to the second
+	// column, so the cells of a row would otherwise make one line.
+	marks := []extractor.TextMark{
+		word("Apple pie", 91, 184), {Text: " ", Meta: true}, word("Cheap", 155, 184), {Text: "\n", Meta: true},
+		word("Fig", 127, 164), {Text: " ", Meta: true}, word("Dear", 155, 164),
+	}
+	mediaBox := model.PdfRectangle{Urx: 300, Ury: 300}
+	if blocks := pageBlocks(marks, mediaBox, 0, nil); len(blocks) != 2 || blocks[0].Text != "Apple pie Cheap" {
+		t.Fatalf("without rules: %+v", blocks)
+	}
+
+	rules := []model.PdfRectangle{
+		hrule(50, 250, 200), hrule(50, 250, 180), hrule(50, 250, 160),
+		vrule(50, 160, 200), vrule(150, 160, 200), vrule(250, 160, 200),
+	}
+	blocks := pageBlocks(marks, mediaBox, 0, rules)
+	var texts []string
+	for _, b := range blocks {
+		texts = append(texts, b.Text)
+		if b.Text == "Cheap" && b.Cell != (model.PdfRectangle{Llx: 150, Lly: 180, Urx: 250, Ury: 200}) {
+			t.Fatalf("cell=%+v", b.Cell)
+		}
+	}
+	sort.Strings(texts)
+	if got := strings.Join(texts, "|"); got != "Apple pie|Cheap|Dear|Fig" {
+		t.Fatalf("blocks=%q", got)
+	}
+}
+
+func TestFindTablesWithoutVerticalRules(t *testing.T) {
+	marks := []extractor.TextMark{
+		word("Name", 55, 184), word("Price", 200, 184),
+		word("Tea", 55, 164), word("120", 200, 164),
+		word("Coffee", 55, 144), word("150", 200, 144),
+	}
+	rules := []model.PdfRectangle{hrule(50, 350, 200), hrule(50, 350, 180), hrule(50, 350, 160), hrule(50, 350, 140)}
+	tables := findTables(rules, marks)
+	if len(tables) != 1 || len(tables[0].cols) != 3 || len(tables[0].rows) != 4 {
+		t.Fatalf("tables=%+v", tables)
+	}
+	if gutter := tables[0].cols[1]; gutter <= 91 || gutter >= 200 {
+		t.Fatalf("cols=%v", tables[0].cols)
+	}
+
+	// A header and a footer rule alone are not a table.
+	if tables := findTables([]model.PdfRectangle{hrule(50, 350, 750), hrule(50, 350, 40)}, marks); len(tables) != 0 {
+		t.Fatalf("header and footer: %+v", tables)
+	}
+}
+
+func TestFindTablesIgnoresRuleFramedColumns(t *testing.T) {
+	// Two columns of text between a rule under the heading, a rule between
+	// two sections and a rule above the footer.
+	var marks []extractor.TextMark
+	for y := 350.0; y < 690; y += 14 {
+		marks = append(marks, word("Left column text", 72, y), word("Right column text", 320, y))
+	}
+	rules := []model.PdfRectangle{hrule(72, 540, 700), hrule(72, 540, 520), hrule(72, 540, 340)}
+	if tables := findTables(rules, marks); len(tables) != 0 {
+		t.Fatalf("tables=%+v", tables)
+	}
+}
+
+func TestCellTranslationShrinksToCell(t *testing.T) {
+	line := textLine{Text: "Cheap", Box: model.PdfRectangle{Llx: 155, Lly: 184, Urx: 185, Ury: 194}, FontSize: 10}
+	block := textBlock{Lines: []textLine{line}, Text: line.Text, Box: line.Box, Cell: model.PdfRectangle{Llx: 150, Lly: 160, Urx: 250, Ury: 200}}
+	block.Box = cellArea(block)
+	fit := fitBlock(block, "much cheaper than anything else on the menu today", 0, 4, monospace)
+	if fit.overflow || fit.size >= 10 {
+		t.Fatalf("fit=%+v", fit)
+	}
+	for _, l := range fit.lines {
+		if w := monospace(l) * fit.size; w > block.Box.Urx-block.Box.Llx {
+			t.Fatalf("line %q is %gpt wide", l, w)
+		}
+	}
+}
@@ -66,6 +66,22 @@ func (r *Reporter) Done() {
 	fmt.Fprint(r.out, "\n")
 }
 
+// Write prints p, such as a warning, above the progress line, which is
+// drawn again below it.
+func (r *Reporter) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.count > 0 {
+		fmt.Fprint(r.out, "\r\x1b[2K")
+	}
+	n, err := r.out.Write(p)
+	if err == nil && r.count > 0 {
+		r.printLocked(r.count)
+	}
+	return n, err
+}
+
 func (r *Reporter) SetTotal(total int) {
 	if r == nil {
 		return
@@ -44,3 +44,18 @@ func TestReporterWithTotal(t *testing.T) {
 		t.Fatalf("expected trailing newline, got %q", out)
 	}
 }
+
+func TestReporterWriteKeepsProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	r := New(&buf, WithMinInterval(0))
+	r.Tick("")
+	if _, err := r.Write([]byte("page 1: warning\n")); err != nil {
+		t.Fatal(err)
+	}
+	r.Done()
+
+	want := "\rtranslating... 1\r\x1b[2Kpage 1: warning\n\rtranslating... 1\rtranslating... 1\n"
+	if out := buf.String(); out != want {
+		t.Fatalf("got %q want %q", out, want)
+	}
+}
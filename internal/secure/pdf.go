@@ -0,0 +1,32 @@
+package secure
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/term"
+)
+
+const pdfPasswordEnv = "TRANSLATE_PDF_PASSWORD"
+
+// PDFPassword returns the password for encrypted PDF input: value when set,
+// otherwise TRANSLATE_PDF_PASSWORD.
+func PDFPassword(value string) string {
+	if value != "" {
+		return value
+	}
+	return os.Getenv(pdfPasswordEnv)
+}
+
+// PromptPDFPassword asks for the password of an encrypted PDF on the
+// terminal.
+func PromptPDFPassword() (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", errors.New("encrypted pdf requires password (set --pdf-password or TRANSLATE_PDF_PASSWORD)")
+	}
+	p, err := promptHidden("PDF password: ")
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
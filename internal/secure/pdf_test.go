@@ -0,0 +1,13 @@
+package secure
+
+import "testing"
+
+func TestPDFPassword(t *testing.T) {
+	t.Setenv(pdfPasswordEnv, "from-env")
+	if got := PDFPassword("from-flag"); got != "from-flag" {
+		t.Fatalf("got %q", got)
+	}
+	if got := PDFPassword(""); got != "from-env" {
+		t.Fatalf("got %q", got)
+	}
+}